@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"gophermart/internal/core/services/logging"
+	"gophermart/internal/core/services/server"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-contrib/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+func main() {
+	logService := logging.New()
+	mainLogger := logService.ComponentLogger("AccrualFake")
+
+	var address, fixturePath string
+	flag.StringVar(&address, "a", ":8081", "Server address")
+	flag.StringVar(&fixturePath, "f", "", "Path to the JSON fixture file")
+	flag.Parse()
+
+	fixture := Fixture{}
+	if fixturePath != "" {
+		var err error
+		if fixture, err = LoadFixture(fixturePath); err != nil {
+			mainLogger.Fatal().Err(err).Msg("failed to load fixture")
+		}
+	}
+
+	engine := gin.New()
+	engine.Use(gin.Recovery(), logger.SetLogger())
+	NewAPI(NewScenarios(fixture)).Register(engine)
+
+	srv := server.NewServer(address, engine, logService)
+	srv.Start()
+	defer srv.Stop(context.Background())
+
+	mainLogger.Info().Str("address", address).Msg("fake accrual system is started")
+
+	waitSigterm(mainLogger)
+}
+
+type API struct {
+	scenarios *Scenarios
+}
+
+func NewAPI(scenarios *Scenarios) *API {
+	return &API{scenarios: scenarios}
+}
+
+func (api *API) Register(engine *gin.Engine) {
+	engine.GET("/api/orders/:number", api.getOrderHandler)
+
+	adminGroup := engine.Group("/admin")
+	adminGroup.GET("/fixture", api.getFixtureHandler)
+	adminGroup.PUT("/default", api.setDefaultHandler)
+	adminGroup.PUT("/orders/:number", api.setOrderHandler)
+	adminGroup.DELETE("/orders/:number", api.deleteOrderHandler)
+	adminGroup.POST("/reset", api.resetHandler)
+}
+
+func (api *API) getOrderHandler(c *gin.Context) {
+	number := c.Param("number")
+
+	step, ok := api.scenarios.Next(number)
+	if !ok {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if step.Delay > 0 {
+		select {
+		case <-time.After(time.Duration(step.Delay)):
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+
+	switch step.HTTPStatus {
+	case 0, http.StatusOK:
+		body := gin.H{"order": number, "status": step.Status}
+		if step.Accrual != nil {
+			body["accrual"] = *step.Accrual
+		}
+		c.JSON(http.StatusOK, body)
+	case http.StatusTooManyRequests:
+		if step.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(step.RetryAfter))
+		}
+		c.String(http.StatusTooManyRequests, "No more than N requests per minute allowed")
+	default:
+		c.Status(step.HTTPStatus)
+	}
+}
+
+func (api *API) getFixtureHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, api.scenarios.Fixture())
+}
+
+func (api *API) setDefaultHandler(c *gin.Context) {
+	var scenario *Scenario
+	if err := c.ShouldBindJSON(&scenario); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	api.scenarios.SetDefault(scenario)
+	c.Status(http.StatusNoContent)
+}
+
+func (api *API) setOrderHandler(c *gin.Context) {
+	var scenario Scenario
+	if err := c.ShouldBindJSON(&scenario); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	api.scenarios.SetOrder(c.Param("number"), scenario)
+	c.Status(http.StatusNoContent)
+}
+
+func (api *API) deleteOrderHandler(c *gin.Context) {
+	api.scenarios.DeleteOrder(c.Param("number"))
+	c.Status(http.StatusNoContent)
+}
+
+func (api *API) resetHandler(c *gin.Context) {
+	api.scenarios.Reset()
+	c.Status(http.StatusNoContent)
+}
+
+func waitSigterm(logger zerolog.Logger) {
+	quit := make(chan os.Signal, 2)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.Warn().Msg("Server was interrupted. Shutdown server.")
+}
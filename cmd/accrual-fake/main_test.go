@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrderHandler(t *testing.T) {
+	accrual := 500.5
+	scenarios := NewScenarios(Fixture{
+		Orders: map[string]Scenario{
+			"1": {Steps: []Step{
+				{Status: "REGISTERED"},
+				{HTTPStatus: http.StatusTooManyRequests, RetryAfter: 60},
+				{HTTPStatus: http.StatusInternalServerError},
+				{Status: "PROCESSED", Accrual: &accrual},
+			}},
+		},
+	})
+
+	router := gin.New()
+	NewAPI(scenarios).Register(router)
+
+	type want struct {
+		status     int
+		retryAfter string
+		body       string
+	}
+	tests := []struct {
+		name  string
+		order string
+		want  want
+	}{
+		{
+			name:  "unknown order",
+			order: "2",
+			want:  want{status: http.StatusNoContent},
+		},
+		{
+			name:  "first step",
+			order: "1",
+			want:  want{status: http.StatusOK, body: `{"order":"1","status":"REGISTERED"}`},
+		},
+		{
+			name:  "too many requests",
+			order: "1",
+			want:  want{status: http.StatusTooManyRequests, retryAfter: "60"},
+		},
+		{
+			name:  "internal error",
+			order: "1",
+			want:  want{status: http.StatusInternalServerError},
+		},
+		{
+			name:  "last step",
+			order: "1",
+			want:  want{status: http.StatusOK, body: `{"accrual":500.5,"order":"1","status":"PROCESSED"}`},
+		},
+		{
+			name:  "last step is repeated",
+			order: "1",
+			want:  want{status: http.StatusOK, body: `{"accrual":500.5,"order":"1","status":"PROCESSED"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/orders/"+tt.order, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want.status, w.Code)
+			assert.Equal(t, tt.want.retryAfter, w.Header().Get("Retry-After"))
+			if tt.want.body != "" {
+				assert.JSONEq(t, tt.want.body, w.Body.String())
+			}
+		})
+	}
+}
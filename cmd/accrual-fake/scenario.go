@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Step is a single answer of the fake accrual system.
+type Step struct {
+	// Status is the accrual status in the body of the answer, e.g. PROCESSED.
+	Status string `json:"status,omitempty"`
+	// Accrual is sent only when it's set.
+	Accrual *float64 `json:"accrual,omitempty"`
+	// HTTPStatus is 200 by default, use 204, 429 or 500 to simulate errors.
+	HTTPStatus int `json:"http_status,omitempty"`
+	// RetryAfter is sent in the Retry-After header of 429 answers, in seconds.
+	RetryAfter int `json:"retry_after,omitempty"`
+	// Delay slows the answer down, e.g. "3s".
+	Delay Duration `json:"delay,omitempty"`
+}
+
+// Scenario is a scripted progression of answers for an order. Every request moves
+// the order to the next step, the last step is repeated forever.
+type Scenario struct {
+	Steps []Step `json:"steps"`
+}
+
+// Fixture describes the behavior of the fake accrual system.
+type Fixture struct {
+	// Default is used for the orders which are not listed in Orders.
+	// Without it such orders are answered with 204.
+	Default *Scenario           `json:"default,omitempty"`
+	Orders  map[string]Scenario `json:"orders,omitempty"`
+}
+
+func LoadFixture(path string) (Fixture, error) {
+	var fixture Fixture
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fixture, errors.Wrapf(err, "failed to read fixture file %s", path)
+	}
+
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return fixture, errors.Wrapf(err, "failed to parse fixture file %s", path)
+	}
+
+	return fixture, nil
+}
+
+// Scenarios keeps the fixture and the progress of every order.
+type Scenarios struct {
+	mx       sync.Mutex
+	fixture  Fixture
+	progress map[string]int
+}
+
+func NewScenarios(fixture Fixture) *Scenarios {
+	if fixture.Orders == nil {
+		fixture.Orders = make(map[string]Scenario)
+	}
+
+	return &Scenarios{
+		fixture:  fixture,
+		progress: make(map[string]int),
+	}
+}
+
+// Next returns the step which should be played for the order and moves the order forward.
+func (s *Scenarios) Next(orderNumber string) (Step, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	scenario, ok := s.fixture.Orders[orderNumber]
+	if !ok {
+		if s.fixture.Default == nil {
+			return Step{}, false
+		}
+		scenario = *s.fixture.Default
+	}
+
+	if len(scenario.Steps) == 0 {
+		return Step{}, false
+	}
+
+	i := s.progress[orderNumber]
+	if i >= len(scenario.Steps) {
+		i = len(scenario.Steps) - 1
+	}
+	s.progress[orderNumber] = i + 1
+
+	return scenario.Steps[i], true
+}
+
+func (s *Scenarios) SetOrder(orderNumber string, scenario Scenario) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.fixture.Orders[orderNumber] = scenario
+	delete(s.progress, orderNumber)
+}
+
+func (s *Scenarios) DeleteOrder(orderNumber string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	delete(s.fixture.Orders, orderNumber)
+	delete(s.progress, orderNumber)
+}
+
+func (s *Scenarios) SetDefault(scenario *Scenario) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.fixture.Default = scenario
+}
+
+// Reset starts all the scenarios from the beginning.
+func (s *Scenarios) Reset() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.progress = make(map[string]int)
+}
+
+func (s *Scenarios) Fixture() Fixture {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	orders := make(map[string]Scenario, len(s.fixture.Orders))
+	for number, scenario := range s.fixture.Orders {
+		orders[number] = scenario
+	}
+
+	return Fixture{Default: s.fixture.Default, Orders: orders}
+}
+
+// Duration is a time.Duration which is written in JSON as a string, e.g. "1.5s".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.Wrap(err, "duration should be a string")
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Wrapf(err, "invalid duration %s", s)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
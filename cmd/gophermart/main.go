@@ -2,16 +2,37 @@ package main
 
 import (
 	"context"
+	"gophermart/internal/api/accrualapi"
+	"gophermart/internal/api/adminapi"
 	"gophermart/internal/api/userapi"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/accrualrules"
 	"gophermart/internal/core/services/accrualservice"
 	"gophermart/internal/core/services/accrualworker"
 	"gophermart/internal/core/services/config"
 	"gophermart/internal/core/services/db"
+	"gophermart/internal/core/services/expiryworker"
+	"gophermart/internal/core/services/holdservice"
+	"gophermart/internal/core/services/idempotencyservice"
 	"gophermart/internal/core/services/logging"
+	"gophermart/internal/core/services/notifier"
 	"gophermart/internal/core/services/orderservice"
+	"gophermart/internal/core/services/promoservice"
+	"gophermart/internal/core/services/referralservice"
 	"gophermart/internal/core/services/server"
+	"gophermart/internal/core/services/tierservice"
+	"gophermart/internal/core/services/transferservice"
 	"gophermart/internal/core/services/userservice"
+	"gophermart/internal/core/stores/holdstore"
+	"gophermart/internal/core/stores/idempotencystore"
+	"gophermart/internal/core/stores/ledgerstore"
 	"gophermart/internal/core/stores/orderstore"
+	"gophermart/internal/core/stores/promostore"
+	"gophermart/internal/core/stores/referralstore"
+	"gophermart/internal/core/stores/tierstore"
+	"gophermart/internal/core/stores/tokenstore"
+	"gophermart/internal/core/stores/transferstore"
 	"gophermart/internal/core/stores/userstore"
 	"gophermart/internal/core/stores/withdrawstore"
 	"os"
@@ -37,30 +58,147 @@ func main() {
 		mainLogger.Fatal().Err(err).Msg("failed to initiate database")
 	}
 
+	rules, err := accrualrules.Load(conf.AccrualRulesFile)
+	if err != nil {
+		mainLogger.Fatal().Err(err).Msg("failed to load accrual rules")
+	}
+
+	tiers, err := tierservice.LoadTiers(conf.TiersFile)
+	if err != nil {
+		mainLogger.Fatal().Err(err).Msg("failed to load tiers")
+	}
+
 	engine := createEngine()
 
 	// Stores
 	userStore := userstore.New(db)
+	tokenStore := tokenstore.New(db)
 	orderStore := orderstore.New(db)
 	withdrawStore := withdrawstore.New(db)
+	ledgerStore := ledgerstore.New(db)
+	holdStore := holdstore.New(db)
+	transferStore := transferstore.New(db)
+	tierStore := tierstore.New(db)
+	referralStore := referralstore.New(db)
+	promoStore := promostore.New(db)
+	idempotencyStore := idempotencystore.New(db)
 
 	// Services
 	srv := server.NewServer(":8080", engine, logService)
-	userService := userservice.New(conf.Secret, logService, userStore)
-	orderService := orderservice.New(logService, orderStore, withdrawStore)
-	accrualService := accrualservice.New(conf.AccrualSystemAddress, logService)
-	accrualWorker := accrualworker.New(accrualService, orderStore, logService)
+	tierPolicy := domain.TierPolicy{Window: conf.TierWindow, Tiers: tiers}
+	accrualRules := accrualrules.New(logService, ledgerStore, tierStore, rules, tierPolicy)
+	referralService := referralservice.New(logService, referralStore, domain.ReferralPolicy{
+		ReferrerBonus: conf.ReferrerBonus,
+		RefereeBonus:  conf.RefereeBonus,
+		MaxReferrals:  conf.MaxReferrals,
+	})
+	// Without the notifier the password resets are disabled
+	var userNotifier ports.Notifier
+	if conf.NotifierSender != "" {
+		n, err := notifier.New(logService, conf.NotifierSender, conf.NotificationsFile)
+		if err != nil {
+			mainLogger.Fatal().Err(err).Msg("failed to create notifier")
+		}
+		defer n.Close()
+		userNotifier = n
+	} else {
+		mainLogger.Warn().Msg("notifier sender is not set, password resets are disabled")
+	}
+
+	userService := userservice.New(
+		conf.Secret, logService, userStore, tokenStore, accrualRules, referralService, userNotifier, userservice.Options{
+			AccessTokenTTL:   conf.AccessTokenTTL,
+			RefreshTokenTTL:  conf.RefreshTokenTTL,
+			PasswordResetTTL: conf.PasswordResetTTL,
+		},
+	)
+	expiryPolicy := domain.ExpiryPolicy{Months: conf.PointsExpiryMonths, Notice: conf.PointsExpiryNotice}
+	orderService := orderservice.New(
+		logService, orderStore, withdrawStore, ledgerStore, tierStore, expiryPolicy, tierPolicy,
+	)
+	tierService := tierservice.New(logService, tierStore, tierPolicy, conf.TierInterval)
+	promoService := promoservice.New(logService, promoStore)
+	holdService := holdservice.New(logService, holdStore, tierStore, tierPolicy, conf.HoldTTL)
+	transferService := transferservice.New(logService, transferStore, domain.TransferLimits{
+		MaxAmount:   conf.TransferMaxAmount,
+		DailyAmount: conf.TransferDailyAmount,
+	})
+	expiryWorker := expiryworker.New(ledgerStore, logService, expiryPolicy, conf.PointsExpiryInterval)
+	idempotencyService := idempotencyservice.New(
+		logService, idempotencyStore, conf.IdempotencyKeyTTL, conf.IdempotencyKeyLease,
+	)
+	accrualService := accrualservice.NewCircuitBreaker(
+		accrualservice.New(conf.AccrualSystemAddress, logService, accrualservice.Options{
+			ConnectTimeout: conf.AccrualConnectTimeout,
+			ReadTimeout:    conf.AccrualReadTimeout,
+			MaxIdleConns:   conf.AccrualMaxIdleConns,
+			Retries:        conf.AccrualRetries,
+			RetryDelay:     conf.AccrualRetryDelay,
+		}),
+		logService,
+		conf.AccrualBreakerThreshold,
+		conf.AccrualBreakerTimeout,
+	)
+	accrualWorker := accrualworker.New(accrualService, orderStore, accrualRules, logService, accrualworker.Options{
+		Workers:    conf.AccrualWorkers,
+		RateLimit:  conf.AccrualRateLimit,
+		BatchSize:  conf.AccrualBatchSize,
+		MinBackoff: conf.AccrualMinBackoff,
+		MaxBackoff: conf.AccrualMaxBackoff,
+		MaxAge:     conf.AccrualMaxAge,
+		ClaimLimit: conf.AccrualClaimLimit,
+		Lease:      conf.AccrualLease,
+	})
 
 	// APIs
-	userAPI := userapi.New(logService, userService, orderService)
+	userAPI := userapi.New(
+		logService,
+		userService,
+		orderService,
+		holdService,
+		transferService,
+		tierService,
+		referralService,
+		promoService,
+		idempotencyService,
+	)
 	userAPI.Register(engine)
 
+	adminAPI := adminapi.New(logService, orderService, promoService, conf.AdminToken)
+	adminAPI.Register(engine)
+
+	if conf.AccrualPush() {
+		accrualAPI := accrualapi.New(logService, accrualWorker, conf.AccrualCallbackSecret)
+		accrualAPI.Register(engine)
+	}
+
 	// Start
 	srv.Start()
 	defer srv.Stop(context.Background())
 
-	accrualWorker.Run()
-	defer accrualWorker.Stop()
+	userService.Run()
+	defer userService.Stop()
+
+	idempotencyService.Run()
+	defer idempotencyService.Stop()
+
+	holdService.Run()
+	defer holdService.Stop()
+
+	if conf.AccrualPoll() {
+		accrualWorker.Run()
+		defer accrualWorker.Stop()
+	}
+
+	if expiryPolicy.Enabled() {
+		expiryWorker.Run()
+		defer expiryWorker.Stop()
+	}
+
+	if tierPolicy.Enabled() {
+		tierService.Run()
+		defer tierService.Stop()
+	}
 
 	waitSigterm(mainLogger)
 }
@@ -0,0 +1,103 @@
+package accrualapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// SignatureHeaderName contains hex encoded HMAC-SHA256 of the request body.
+var SignatureHeaderName = "X-Accrual-Signature"
+
+// AccrualAPI receives the results pushed by the accrual system.
+type AccrualAPI struct {
+	logger           zerolog.Logger
+	accrualProcessor ports.AccrualProcessor
+	secret           string
+}
+
+func New(
+	logService *logging.LoggerService,
+	accrualProcessor ports.AccrualProcessor,
+	secret string,
+) *AccrualAPI {
+	return &AccrualAPI{
+		logger:           logService.ComponentLogger("AccrualAPI"),
+		accrualProcessor: accrualProcessor,
+		secret:           secret,
+	}
+}
+
+func (api *AccrualAPI) Register(engine *gin.Engine) {
+	accrualGroup := engine.Group("/api/internal/accrual")
+
+	accrualGroup.POST("/callback", api.callbackHandler)
+}
+
+func (api *AccrualAPI) callbackHandler(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		reportError(c, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !api.validSignature(body, c.GetHeader(SignatureHeaderName)) {
+		reportError(c, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var resp ports.AccrualResponse
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(&resp); err != nil || resp.Order == "" {
+		reportError(c, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	err = api.accrualProcessor.ProcessAccrual(c, resp)
+	switch {
+	case errors.Is(err, apperrors.ErrNoSuchOrder):
+		reportError(c, "no such order", http.StatusNotFound)
+	case errors.Is(err, apperrors.ErrUnknownAccrualStatus):
+		reportError(c, "unknown accrual status", http.StatusUnprocessableEntity)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to process accrual")
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": "OK"})
+	}
+}
+
+func (api *AccrualAPI) validSignature(body []byte, signature string) bool {
+	// Without a secret nobody can be authenticated
+	if api.secret == "" || signature == "" {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(expected, Sign(body, api.secret))
+}
+
+// Sign calculates the signature the accrual system should send with the body.
+func Sign(body []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func reportError(c *gin.Context, msg string, status int) {
+	c.AbortWithStatusJSON(status, gin.H{"error": msg})
+}
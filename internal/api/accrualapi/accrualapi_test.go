@@ -0,0 +1,112 @@
+package accrualapi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallbackHandler(t *testing.T) {
+	secret := "secret"
+	body := []byte(`{"order": "12345678903", "status": "PROCESSED", "accrual": 500}`)
+	validSignature := hex.EncodeToString(Sign(body, secret))
+
+	tests := []struct {
+		name           string
+		body           []byte
+		signature      string
+		processErr     error
+		processExpects bool
+
+		wantStatus int
+	}{
+		{
+			name:       "missing signature",
+			body:       body,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong signature",
+			body:       body,
+			signature:  hex.EncodeToString(Sign(body, "another secret")),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid body",
+			body:       []byte("hello there"),
+			signature:  hex.EncodeToString(Sign([]byte("hello there"), secret)),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown order",
+			body:           body,
+			signature:      validSignature,
+			processExpects: true,
+			processErr:     errors.Wrap(apperrors.ErrNoSuchOrder, "test error"),
+			wantStatus:     http.StatusNotFound,
+		},
+		{
+			name:           "unknown status",
+			body:           body,
+			signature:      validSignature,
+			processExpects: true,
+			processErr:     errors.Wrap(apperrors.ErrUnknownAccrualStatus, "test error"),
+			wantStatus:     http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "internal error",
+			body:           body,
+			signature:      validSignature,
+			processExpects: true,
+			processErr:     errors.New("test error"),
+			wantStatus:     http.StatusInternalServerError,
+		},
+		{
+			name:           "success case",
+			body:           body,
+			signature:      validSignature,
+			processExpects: true,
+			wantStatus:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accrualProcessor := mocks.NewMockAccrualProcessor(ctrl)
+			router := gin.New()
+			New(logging.New(), accrualProcessor, secret).Register(router)
+
+			if tt.processExpects {
+				accrualProcessor.EXPECT().
+					ProcessAccrual(gomock.Any(), ports.AccrualResponse{
+						Order:   "12345678903",
+						Status:  domain.AccrualStatusProcessed,
+						Accrual: 50000,
+					}).
+					Return(tt.processErr).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/internal/accrual/callback", bytes.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeaderName, tt.signature)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
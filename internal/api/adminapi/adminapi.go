@@ -0,0 +1,132 @@
+package adminapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// TokenHeaderName contains the token shared with the operators and the shop backend.
+var TokenHeaderName = "X-Admin-Token"
+
+// publishedVars are the only metrics shown to the operators, the rest of expvar
+// (e.g. the command line with the database URI) must not leave the service.
+var publishedVars = []string{"accrual_circuit_breaker"}
+
+// AdminAPI is used by the operators and the shop backend, it's not available to the users.
+type AdminAPI struct {
+	logger       zerolog.Logger
+	orderService ports.OrderService
+	promoService ports.PromoService
+	token        string
+}
+
+func New(
+	logService *logging.LoggerService,
+	orderService ports.OrderService,
+	promoService ports.PromoService,
+	token string,
+) *AdminAPI {
+	return &AdminAPI{
+		logger:       logService.ComponentLogger("AdminAPI"),
+		orderService: orderService,
+		promoService: promoService,
+		token:        token,
+	}
+}
+
+func (api *AdminAPI) Register(engine *gin.Engine) {
+	adminGroup := engine.Group("/api/internal", api.AuthMiddleware)
+
+	adminGroup.GET("/orders/:order", api.orderHandler)
+	adminGroup.POST("/withdrawals/:order/complete", api.completeWithdrawalHandler)
+	adminGroup.POST("/withdrawals/:order/reverse", api.reverseWithdrawalHandler)
+	adminGroup.POST("/promo-codes", api.createPromoCodeHandler)
+	adminGroup.GET("/debug/vars", api.varsHandler)
+}
+
+func (api *AdminAPI) AuthMiddleware(c *gin.Context) {
+	token := c.GetHeader(TokenHeaderName)
+
+	// Without a token nobody can be authenticated
+	if api.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(api.token)) != 1 {
+		reportError(c, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	c.Next()
+}
+
+// varsHandler shows the published metrics in the expvar format.
+func (api *AdminAPI) varsHandler(c *gin.Context) {
+	vars := make(map[string]json.RawMessage, len(publishedVars))
+	for _, name := range publishedVars {
+		if v := expvar.Get(name); v != nil {
+			vars[name] = json.RawMessage(v.String())
+		}
+	}
+
+	c.JSON(http.StatusOK, vars)
+}
+
+// orderHandler shows how the accrual of the order was calculated.
+func (api *AdminAPI) orderHandler(c *gin.Context) {
+	order, err := api.orderService.GetOrder(c, c.Param("order"))
+	switch {
+	case errors.Is(err, apperrors.ErrNoSuchOrder):
+		reportError(c, "no such order", http.StatusNotFound)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Str("order", c.Param("order")).Msg("failed to get order")
+	default:
+		c.JSON(http.StatusOK, order.ToAudit())
+	}
+}
+
+func (api *AdminAPI) completeWithdrawalHandler(c *gin.Context) {
+	err := api.orderService.CompleteWithdrawal(c, c.Param("order"))
+	api.reportWithdrawalResult(c, err, "failed to complete withdrawal")
+}
+
+type reverseRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (api *AdminAPI) reverseWithdrawalHandler(c *gin.Context) {
+	var request reverseRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	err := api.orderService.ReverseWithdrawal(c, c.Param("order"), request.Reason)
+	api.reportWithdrawalResult(c, err, "failed to reverse withdrawal")
+}
+
+func (api *AdminAPI) reportWithdrawalResult(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, apperrors.ErrNoSuchWithdrawal):
+		reportError(c, "no such withdrawal", http.StatusNotFound)
+	case errors.Is(err, apperrors.ErrWithdrawalReversed):
+		reportError(c, "withdrawal is already reversed", http.StatusConflict)
+	case errors.Is(err, apperrors.ErrReversalReasonIsEmpty):
+		reportError(c, "reason is empty", http.StatusBadRequest)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Str("order", c.Param("order")).Msg(msg)
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": "OK"})
+	}
+}
+
+func reportError(c *gin.Context, msg string, status int) {
+	c.AbortWithStatusJSON(status, gin.H{"error": msg})
+}
@@ -0,0 +1,179 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"expvar"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "token"
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		apiToken   string
+		wantStatus int
+	}{
+		{name: "missing token", apiToken: testToken, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: "another token", apiToken: testToken, wantStatus: http.StatusUnauthorized},
+		{name: "token is not configured", apiToken: "", wantStatus: http.StatusUnauthorized},
+		{name: "success case", token: testToken, apiToken: testToken, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			orderService := mocks.NewMockOrderService(ctrl)
+			router := gin.New()
+			New(logging.New(), orderService, mocks.NewMockPromoService(ctrl), tt.apiToken).Register(router)
+
+			if tt.wantStatus == http.StatusOK {
+				orderService.EXPECT().CompleteWithdrawal(gomock.Any(), "2377225624").Return(nil).Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/internal/withdrawals/2377225624/complete", nil)
+			if tt.token != "" {
+				req.Header.Set(TokenHeaderName, tt.token)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestReverseWithdrawalHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestBody   string
+		reverseExpect bool
+		reverseErr    error
+
+		wantStatus int
+	}{
+		{
+			name:        "invalid body",
+			requestBody: `{}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:          "unknown withdrawal",
+			requestBody:   `{"reason": "order is cancelled"}`,
+			reverseExpect: true,
+			reverseErr:    errors.Wrap(apperrors.ErrNoSuchWithdrawal, "test"),
+			wantStatus:    http.StatusNotFound,
+		},
+		{
+			name:          "already reversed",
+			requestBody:   `{"reason": "order is cancelled"}`,
+			reverseExpect: true,
+			reverseErr:    errors.Wrap(apperrors.ErrWithdrawalReversed, "test"),
+			wantStatus:    http.StatusConflict,
+		},
+		{
+			name:          "internal error",
+			requestBody:   `{"reason": "order is cancelled"}`,
+			reverseExpect: true,
+			reverseErr:    errors.New("test"),
+			wantStatus:    http.StatusInternalServerError,
+		},
+		{
+			name:          "success case",
+			requestBody:   `{"reason": "order is cancelled"}`,
+			reverseExpect: true,
+			wantStatus:    http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			orderService := mocks.NewMockOrderService(ctrl)
+			router := gin.New()
+			New(logging.New(), orderService, mocks.NewMockPromoService(ctrl), testToken).Register(router)
+
+			if tt.reverseExpect {
+				orderService.EXPECT().
+					ReverseWithdrawal(gomock.Any(), "2377225624", "order is cancelled").
+					Return(tt.reverseErr).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/internal/withdrawals/2377225624/reverse", strings.NewReader(tt.requestBody))
+			req.Header.Set(TokenHeaderName, testToken)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestOrderHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	orderService := mocks.NewMockOrderService(ctrl)
+	router := gin.New()
+	New(logging.New(), orderService, mocks.NewMockPromoService(ctrl), testToken).Register(router)
+
+	order := domain.Order{
+		OrderNumber:  "2377225624",
+		Status:       domain.OrderStatusProcessed,
+		BaseAccrual:  1000,
+		Accrual:      2000,
+		AppliedRules: domain.AppliedRules{{Name: "x2", Type: "multiplier", Accrual: 2000}},
+	}
+	orderService.EXPECT().GetOrder(gomock.Any(), "2377225624").Return(order, nil)
+	orderService.EXPECT().GetOrder(gomock.Any(), "1").Return(domain.Order{}, apperrors.ErrNoSuchOrder)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/internal/orders/2377225624", nil)
+	req.Header.Set(TokenHeaderName, testToken)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var audit domain.OrderAudit
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &audit))
+	assert.Equal(t, order.ToAudit(), audit)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/internal/orders/1", nil)
+	req.Header.Set(TokenHeaderName, testToken)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestVarsHandler(t *testing.T) {
+	breaker := expvar.NewMap("accrual_circuit_breaker")
+	breaker.Add("opened_total", 1)
+
+	ctrl := gomock.NewController(t)
+	router := gin.New()
+	New(logging.New(), mocks.NewMockOrderService(ctrl), mocks.NewMockPromoService(ctrl), testToken).Register(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/internal/debug/vars", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	req.Header.Set(TokenHeaderName, testToken)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The command line and the memory stats are not published
+	assert.JSONEq(t, `{"accrual_circuit_breaker": {"opened_total": 1}}`, w.Body.String())
+}
@@ -0,0 +1,61 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type promoCodeRequest struct {
+	Code           string      `json:"code" binding:"required"`
+	Amount         json.Number `json:"amount"`
+	MaxRedemptions int         `json:"max_redemptions"`
+	// MaxPerUser is 1 when it's omitted, 0 means no limit
+	MaxPerUser *int       `json:"max_per_user"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidTo    *time.Time `json:"valid_to"`
+}
+
+func (api *AdminAPI) createPromoCodeHandler(c *gin.Context) {
+	var request promoCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	amount, err := domain.ParseExactMoney(request.Amount.String())
+	if err != nil {
+		reportError(c, "invalid amount", http.StatusUnprocessableEntity)
+		return
+	}
+
+	promo := domain.PromoCode{
+		Code:           request.Code,
+		Amount:         amount,
+		MaxRedemptions: request.MaxRedemptions,
+		MaxPerUser:     1,
+		ValidFrom:      request.ValidFrom,
+		ValidTo:        request.ValidTo,
+	}
+	if request.MaxPerUser != nil {
+		promo.MaxPerUser = *request.MaxPerUser
+	}
+
+	created, err := api.promoService.CreatePromoCode(c, promo)
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidPromoCode):
+		reportError(c, "invalid promo code", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrPromoCodeAlreadyExists):
+		reportError(c, "promo code already exists", http.StatusConflict)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Str("code", request.Code).Msg("failed to create promo code")
+	default:
+		c.JSON(http.StatusCreated, created.ToDisplay())
+	}
+}
@@ -0,0 +1,83 @@
+package adminapi
+
+import (
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePromoCodeHandler(t *testing.T) {
+	validTo := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		requestBody string
+		wantPromo   *domain.PromoCode
+		createErr   error
+
+		wantStatus int
+	}{
+		{
+			name:        "invalid body",
+			requestBody: `{"amount": 100}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "too precise amount",
+			requestBody: `{"code": "spring", "amount": 0.001}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "already exists",
+			requestBody: `{"code": "spring", "amount": 100}`,
+			wantPromo:   &domain.PromoCode{Code: "spring", Amount: 10000, MaxPerUser: 1},
+			createErr:   errors.Wrap(apperrors.ErrPromoCodeAlreadyExists, "test"),
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "success case",
+			requestBody: `{"code": "spring", "amount": 100, "max_redemptions": 1000, "max_per_user": 0, "valid_to": "2023-04-01T00:00:00Z"}`,
+			wantPromo: &domain.PromoCode{
+				Code:           "spring",
+				Amount:         10000,
+				MaxRedemptions: 1000,
+				MaxPerUser:     0,
+				ValidTo:        &validTo,
+			},
+			wantStatus: http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			promoService := mocks.NewMockPromoService(ctrl)
+			router := gin.New()
+			New(logging.New(), mocks.NewMockOrderService(ctrl), promoService, testToken).Register(router)
+
+			if tt.wantPromo != nil {
+				promoService.EXPECT().
+					CreatePromoCode(gomock.Any(), *tt.wantPromo).
+					Return(*tt.wantPromo, tt.createErr).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/internal/promo-codes", strings.NewReader(tt.requestBody))
+			req.Header.Set(TokenHeaderName, testToken)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -24,7 +24,15 @@ func (api *UserAPI) AuthMiddleware(c *gin.Context) {
 	}
 
 	user, err := api.userService.AuthenticateUser(c, split[1])
-	if errors.Is(err, apperrors.ErrAuthFailed) {
+	if errors.Is(err, apperrors.ErrTokenExpired) {
+		// The client should refresh the token instead of asking the user to log in
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token", error_description="token is expired"`)
+		reportError(c, "token is expired", http.StatusUnauthorized)
+		return
+	} else if errors.Is(err, apperrors.ErrTokenRevoked) {
+		reportError(c, "token is revoked", http.StatusUnauthorized)
+		return
+	} else if errors.Is(err, apperrors.ErrAuthFailed) {
 		reportError(c, "auth incorrect", http.StatusUnauthorized)
 		return
 	} else if err != nil {
@@ -35,6 +43,7 @@ func (api *UserAPI) AuthMiddleware(c *gin.Context) {
 	}
 
 	c.Set(UserKey, user)
+	c.Set(TokenKey, split[1])
 
 	c.Next()
 }
@@ -43,3 +52,8 @@ func (api *UserAPI) GetUser(c *gin.Context) domain.User {
 	user := c.MustGet(UserKey)
 	return user.(domain.User)
 }
+
+// GetToken returns the access token the user is authenticated with.
+func (api *UserAPI) GetToken(c *gin.Context) string {
+	return c.GetString(TokenKey)
+}
@@ -0,0 +1,90 @@
+package userapi
+
+import (
+	"context"
+	"encoding/json"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type holdRequest struct {
+	Order string      `json:"order"`
+	Sum   json.Number `json:"sum"`
+}
+
+func (api *UserAPI) createHoldHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	var request holdRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "wrong hold", http.StatusBadRequest)
+		return
+	}
+
+	sum, err := domain.ParseExactMoney(request.Sum.String())
+	if err != nil {
+		reportError(c, "invalid sum", http.StatusUnprocessableEntity)
+		return
+	}
+
+	hold, err := api.holdService.CreateHold(c, request.Order, sum, &user)
+	switch {
+	case errors.Is(err, apperrors.ErrNotEnoughMoney):
+		reportError(c, "not enough money", http.StatusPaymentRequired)
+	case errors.Is(err, apperrors.ErrIncorrectOrderFormat):
+		reportError(c, "incorrect order format", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrInvalidWithdrawSum):
+		reportError(c, "invalid sum", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrHoldAlreadyExists):
+		reportError(c, "hold for this order already exists", http.StatusConflict)
+	case errors.Is(err, apperrors.ErrWithdrawalLimitExceeded):
+		reportError(c, "withdrawal limit of the tier is exceeded", http.StatusUnprocessableEntity)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to create hold")
+	default:
+		c.JSON(http.StatusCreated, hold.ToDisplay())
+	}
+}
+
+func (api *UserAPI) captureHoldHandler(c *gin.Context) {
+	api.finishHold(c, api.holdService.CaptureHold)
+}
+
+func (api *UserAPI) releaseHoldHandler(c *gin.Context) {
+	api.finishHold(c, api.holdService.ReleaseHold)
+}
+
+// finishHold captures or releases the hold from the path.
+func (api *UserAPI) finishHold(
+	c *gin.Context,
+	finish func(ctx context.Context, holdID int, user *domain.User) (domain.Hold, error),
+) {
+	user := api.GetUser(c)
+
+	holdID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		reportError(c, "hold id should be a number", http.StatusBadRequest)
+		return
+	}
+
+	hold, err := finish(c, holdID, &user)
+	switch {
+	case errors.Is(err, apperrors.ErrNoSuchHold):
+		reportError(c, "no such hold", http.StatusNotFound)
+	case errors.Is(err, apperrors.ErrHoldNotActive):
+		reportError(c, "hold is already captured, released or expired", http.StatusConflict)
+	case errors.Is(err, apperrors.ErrWithdrawalAlreadyExists):
+		reportError(c, "withdrawal for this order already exists", http.StatusConflict)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Int("hold_id", holdID).Msg("failed to finish hold")
+	default:
+		c.JSON(http.StatusOK, hold.ToDisplay())
+	}
+}
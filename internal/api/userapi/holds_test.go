@@ -0,0 +1,148 @@
+package userapi
+
+import (
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateHoldHandler(t *testing.T) {
+	type holdCall struct {
+		sum     domain.Money
+		returns error
+	}
+	tests := []struct {
+		name        string
+		requestBody string
+		holdCall    *holdCall
+
+		wantStatus int
+	}{
+		{
+			name:        "invalid body",
+			requestBody: `{"order": "2377225624", "sum": "abc"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "too precise sum",
+			requestBody: `{"order": "2377225624", "sum": 0.291}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "not enough money",
+			requestBody: `{"order": "2377225624", "sum": 751}`,
+			holdCall:    &holdCall{sum: 75100, returns: errors.Wrap(apperrors.ErrNotEnoughMoney, "test")},
+			wantStatus:  http.StatusPaymentRequired,
+		},
+		{
+			name:        "duplicate hold",
+			requestBody: `{"order": "2377225624", "sum": 751}`,
+			holdCall:    &holdCall{sum: 75100, returns: errors.Wrap(apperrors.ErrHoldAlreadyExists, "test")},
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "withdrawal limit exceeded",
+			requestBody: `{"order": "2377225624", "sum": 751}`,
+			holdCall:    &holdCall{sum: 75100, returns: errors.Wrap(apperrors.ErrWithdrawalLimitExceeded, "test")},
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "success case",
+			requestBody: `{"order": "2377225624", "sum": 0.29}`,
+			holdCall:    &holdCall{sum: 29},
+			wantStatus:  http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.User{}
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+			if tt.holdCall != nil {
+				apiTest.HoldService.EXPECT().
+					CreateHold(gomock.Any(), "2377225624", tt.holdCall.sum, &user).
+					Return(domain.Hold{}, tt.holdCall.returns).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/user/balance/holds", strings.NewReader(tt.requestBody))
+			req.Header.Set("Authorization", "Bearer authtoken")
+			req.Header.Set("Content-Type", "application/json")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestFinishHoldHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		action     string
+		returns    error
+		wantStatus int
+	}{
+		{
+			name:       "invalid id",
+			path:       "/api/user/balance/holds/abc/capture",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown hold",
+			path:       "/api/user/balance/holds/1/capture",
+			action:     "capture",
+			returns:    errors.Wrap(apperrors.ErrNoSuchHold, "test"),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "expired hold",
+			path:       "/api/user/balance/holds/1/capture",
+			action:     "capture",
+			returns:    errors.Wrap(apperrors.ErrHoldNotActive, "test"),
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "capture",
+			path:       "/api/user/balance/holds/1/capture",
+			action:     "capture",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "release",
+			path:       "/api/user/balance/holds/1/release",
+			action:     "release",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.User{}
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+			switch tt.action {
+			case "capture":
+				apiTest.HoldService.EXPECT().CaptureHold(gomock.Any(), 1, &user).Return(domain.Hold{}, tt.returns).Times(1)
+			case "release":
+				apiTest.HoldService.EXPECT().ReleaseHold(gomock.Any(), 1, &user).Return(domain.Hold{}, tt.returns).Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer authtoken")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package userapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"gophermart/internal/core/apperrors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+var (
+	IdempotencyKeyHeaderName     = "Idempotency-Key"
+	IdempotentReplayedHeaderName = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	abortTimeout            = 5 * time.Second
+)
+
+// IdempotencyMiddleware makes the retries of a request with the same Idempotency-Key header
+// get the response of the first request instead of running the handler again.
+// The requests without the header are passed through. It should be used after AuthMiddleware.
+func (api *UserAPI) IdempotencyMiddleware(c *gin.Context) {
+	key := c.GetHeader(IdempotencyKeyHeaderName)
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		reportError(c, "idempotency key is too long", http.StatusBadRequest)
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		reportError(c, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	user := api.GetUser(c)
+	record, started, err := api.idempotencyService.Begin(c, user.ID, key, requestHash(c, body))
+	switch {
+	case errors.Is(err, apperrors.ErrIdempotencyKeyReused):
+		reportError(c, "idempotency key was used for another request", http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, apperrors.ErrIdempotencyKeyInProgress):
+		reportError(c, "request with such idempotency key is in progress", http.StatusConflict)
+		return
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to check idempotency key")
+		return
+	}
+
+	if !started {
+		c.Header(IdempotentReplayedHeaderName, "true")
+		c.Data(record.StatusCode, record.ContentType, record.Body)
+		c.Abort()
+		return
+	}
+
+	// The key is released unless the response is saved, e.g. when the handler panics,
+	// otherwise the retries would get 409 until the key expires
+	completed := false
+	defer func() {
+		if !completed {
+			api.abortIdempotentRequest(user.ID, key)
+		}
+	}()
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+
+	c.Next()
+
+	// Server errors are not saved, the client is free to retry
+	if recorder.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	record.StatusCode = recorder.Status()
+	record.ContentType = recorder.Header().Get("Content-Type")
+	record.Body = recorder.body.Bytes()
+	if err := api.idempotencyService.Complete(c, record); err != nil {
+		api.logger.Error().Err(err).Msg("failed to save response of idempotent request")
+		return
+	}
+
+	completed = true
+}
+
+// abortIdempotentRequest releases the key even if the request is cancelled by the client.
+func (api *UserAPI) abortIdempotentRequest(userID int, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+
+	if err := api.idempotencyService.Abort(ctx, userID, key); err != nil {
+		api.logger.Error().Err(err).Msg("failed to abort idempotent request")
+	}
+}
+
+// requestHash identifies the request, so the same key can't be used for another one.
+func requestHash(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method))
+	hash.Write([]byte(c.FullPath()))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,33 @@
+package userapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logoutHandler revokes the session the request is authenticated with.
+func (api *UserAPI) logoutHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	if err := api.userService.Logout(c, &user, api.GetToken(c)); err != nil {
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to logout")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "logged out"})
+}
+
+// logoutAllHandler revokes all the sessions of the user, including the current one.
+func (api *UserAPI) logoutAllHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	if err := api.userService.LogoutAll(c, &user); err != nil {
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to logout all sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "all sessions are logged out"})
+}
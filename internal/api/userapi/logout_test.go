@@ -0,0 +1,71 @@
+package userapi
+
+import (
+	"gophermart/internal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogoutHandler(t *testing.T) {
+	user := domain.User{ID: 1, Login: "user"}
+
+	tests := []struct {
+		name   string
+		url    string
+		expect func(apiTest *APITest)
+		want   int
+	}{
+		{
+			name: "logout",
+			url:  "/api/user/logout",
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().
+					Logout(gomock.Any(), &user, "authtoken").
+					Return(nil).
+					Times(1)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "logout fails",
+			url:  "/api/user/logout",
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().
+					Logout(gomock.Any(), &user, "authtoken").
+					Return(errors.New("test error")).
+					Times(1)
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "logout all sessions",
+			url:  "/api/user/logout/all",
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().
+					LogoutAll(gomock.Any(), &user).
+					Return(nil).
+					Times(1)
+			},
+			want: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+			tt.expect(apiTest)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", tt.url, nil)
+			req.Header.Set("Authorization", "Bearer authtoken")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
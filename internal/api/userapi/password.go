@@ -0,0 +1,101 @@
+package userapi
+
+import (
+	"gophermart/internal/core/apperrors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type passwordResetRequest struct {
+	Login string `json:"login"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// changePasswordHandler sets the new password, the other sessions of the user are logged out
+// and the current one gets the new tokens.
+func (api *UserAPI) changePasswordHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	var request changePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := api.userService.ChangePassword(c, &user, request.OldPassword, request.NewPassword)
+	if errors.Is(err, apperrors.ErrPasswordIsEmpty) {
+		reportError(c, "password is empty", http.StatusBadRequest)
+		return
+	} else if errors.Is(err, apperrors.ErrPasswordIncorrect) {
+		reportError(c, "old password is incorrect", http.StatusForbidden)
+		return
+	} else if err != nil {
+		reportError(c, "server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to change password")
+		return
+	}
+
+	respondWithTokens(c, tokens)
+}
+
+// requestPasswordResetHandler sends the reset token to the user. The answer is the same
+// whether the user exists or not.
+func (api *UserAPI) requestPasswordResetHandler(c *gin.Context) {
+	var request passwordResetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	err := api.userService.RequestPasswordReset(c, request.Login)
+	if errors.Is(err, apperrors.ErrLoginIsEmpty) {
+		reportError(c, "login is empty", http.StatusBadRequest)
+		return
+	} else if errors.Is(err, apperrors.ErrPasswordResetDisabled) {
+		reportError(c, "password reset is disabled", http.StatusNotImplemented)
+		return
+	} else if err != nil {
+		reportError(c, "server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to request password reset")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"success": "reset token is sent if the user exists"})
+}
+
+func (api *UserAPI) resetPasswordHandler(c *gin.Context) {
+	var request resetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	err := api.userService.ResetPassword(c, request.Token, request.NewPassword)
+	if errors.Is(err, apperrors.ErrPasswordIsEmpty) {
+		reportError(c, "password is empty", http.StatusBadRequest)
+		return
+	} else if errors.Is(err, apperrors.ErrInvalidResetToken) {
+		reportError(c, "reset token is invalid", http.StatusUnprocessableEntity)
+		return
+	} else if errors.Is(err, apperrors.ErrPasswordResetDisabled) {
+		reportError(c, "password reset is disabled", http.StatusNotImplemented)
+		return
+	} else if err != nil {
+		reportError(c, "server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "password is changed"})
+}
@@ -0,0 +1,149 @@
+package userapi
+
+import (
+	"bytes"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangePasswordHandler(t *testing.T) {
+	user := domain.User{ID: 1, Login: "user"}
+
+	tests := []struct {
+		name    string
+		body    string
+		called  bool
+		returns error
+		want    int
+	}{
+		{name: "invalid body", body: "{", want: http.StatusBadRequest},
+		{
+			name:    "empty password",
+			body:    `{"old_password": "old", "new_password": ""}`,
+			called:  true,
+			returns: apperrors.ErrPasswordIsEmpty,
+			want:    http.StatusBadRequest,
+		},
+		{
+			name:    "wrong old password",
+			body:    `{"old_password": "wrong", "new_password": "new"}`,
+			called:  true,
+			returns: errors.Wrap(apperrors.ErrPasswordIncorrect, "test"),
+			want:    http.StatusForbidden,
+		},
+		{
+			name:   "success case",
+			body:   `{"old_password": "old", "new_password": "new"}`,
+			called: true,
+			want:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+			if tt.called {
+				apiTest.UserService.EXPECT().
+					ChangePassword(gomock.Any(), &user, gomock.Any(), gomock.Any()).
+					Return(domain.Tokens{AccessToken: "token", RefreshToken: "refresh"}, tt.returns).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/user/password", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer authtoken")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, "Bearer token", w.Header().Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestPasswordResetHandlers(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		expect func(apiTest *APITest)
+		want   int
+	}{
+		{
+			name: "reset is requested",
+			url:  "/api/user/password/reset",
+			body: `{"login": "user"}`,
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().RequestPasswordReset(gomock.Any(), "user").Return(nil).Times(1)
+			},
+			want: http.StatusAccepted,
+		},
+		{
+			name: "reset of empty login",
+			url:  "/api/user/password/reset",
+			body: `{"login": ""}`,
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().
+					RequestPasswordReset(gomock.Any(), "").
+					Return(apperrors.ErrLoginIsEmpty).
+					Times(1)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "reset is disabled",
+			url:  "/api/user/password/reset",
+			body: `{"login": "user"}`,
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().
+					RequestPasswordReset(gomock.Any(), "user").
+					Return(errors.Wrap(apperrors.ErrPasswordResetDisabled, "test")).
+					Times(1)
+			},
+			want: http.StatusNotImplemented,
+		},
+		{
+			name: "password is reset",
+			url:  "/api/user/password/reset/confirm",
+			body: `{"token": "token", "new_password": "new"}`,
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().ResetPassword(gomock.Any(), "token", "new").Return(nil).Times(1)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "invalid reset token",
+			url:  "/api/user/password/reset/confirm",
+			body: `{"token": "used", "new_password": "new"}`,
+			expect: func(apiTest *APITest) {
+				apiTest.UserService.EXPECT().
+					ResetPassword(gomock.Any(), "used", "new").
+					Return(errors.Wrap(apperrors.ErrInvalidResetToken, "test")).
+					Times(1)
+			},
+			want: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiTest := NewAPITest(t)
+			tt.expect(apiTest)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", tt.url, bytes.NewBufferString(tt.body))
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
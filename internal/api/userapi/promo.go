@@ -0,0 +1,40 @@
+package userapi
+
+import (
+	"gophermart/internal/core/apperrors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type redeemPromoRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func (api *UserAPI) redeemPromoHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	var request redeemPromoRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "wrong promo code", http.StatusBadRequest)
+		return
+	}
+
+	redemption, err := api.promoService.RedeemPromoCode(c, request.Code, &user)
+	switch {
+	case errors.Is(err, apperrors.ErrNoSuchPromoCode):
+		reportError(c, "no such promo code", http.StatusNotFound)
+	case errors.Is(err, apperrors.ErrPromoCodeNotValid):
+		reportError(c, "promo code is not valid at the moment", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrPromoCodeExhausted):
+		reportError(c, "promo code can't be redeemed anymore", http.StatusConflict)
+	case errors.Is(err, apperrors.ErrPromoCodeAlreadyUsed):
+		reportError(c, "promo code is already redeemed", http.StatusConflict)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to redeem promo code")
+	default:
+		c.JSON(http.StatusOK, redemption.ToDisplay())
+	}
+}
@@ -0,0 +1,80 @@
+package userapi
+
+import (
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedeemPromoHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestBody string
+		redeemCall  bool
+		redeemErr   error
+
+		wantStatus int
+	}{
+		{
+			name:        "missing code",
+			requestBody: `{}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "unknown code",
+			requestBody: `{"code": "spring"}`,
+			redeemCall:  true,
+			redeemErr:   errors.Wrap(apperrors.ErrNoSuchPromoCode, "test"),
+			wantStatus:  http.StatusNotFound,
+		},
+		{
+			name:        "expired code",
+			requestBody: `{"code": "spring"}`,
+			redeemCall:  true,
+			redeemErr:   errors.Wrap(apperrors.ErrPromoCodeNotValid, "test"),
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "already redeemed",
+			requestBody: `{"code": "spring"}`,
+			redeemCall:  true,
+			redeemErr:   errors.Wrap(apperrors.ErrPromoCodeAlreadyUsed, "test"),
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "success case",
+			requestBody: `{"code": "spring"}`,
+			redeemCall:  true,
+			wantStatus:  http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.User{}
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+			if tt.redeemCall {
+				apiTest.PromoService.EXPECT().
+					RedeemPromoCode(gomock.Any(), "spring", &user).
+					Return(domain.PromoRedemption{Code: "SPRING", Amount: 10000}, tt.redeemErr).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/user/promo/redeem", strings.NewReader(tt.requestBody))
+			req.Header.Set("Authorization", "Bearer authtoken")
+			req.Header.Set("Content-Type", "application/json")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
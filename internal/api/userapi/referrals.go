@@ -0,0 +1,35 @@
+package userapi
+
+import (
+	"gophermart/internal/core/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type referralsResponse struct {
+	Code      string                   `json:"code"`
+	Referrals []domain.ReferralDisplay `json:"referrals"`
+}
+
+// referralsHandler shows the referral code of the user and the users invited with it.
+func (api *UserAPI) referralsHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	referrals, err := api.referralService.GetReferrals(c, &user)
+	if err != nil {
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to fetch referrals")
+		return
+	}
+
+	response := referralsResponse{
+		Code:      user.ReferralCode,
+		Referrals: make([]domain.ReferralDisplay, len(referrals)),
+	}
+	for i, referral := range referrals {
+		response.Referrals[i] = referral.ToDisplay()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
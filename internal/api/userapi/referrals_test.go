@@ -0,0 +1,45 @@
+package userapi
+
+import (
+	"gophermart/internal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferralsHandler(t *testing.T) {
+	user := domain.User{ID: 1, Login: "user", ReferralCode: "ABCDEF1234"}
+	apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+	createdAt := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	apiTest.ReferralService.EXPECT().
+		GetReferrals(gomock.Any(), &user).
+		Return([]domain.Referral{
+			{
+				RefereeLogin:  "friend",
+				Status:        domain.ReferralStatusPending,
+				ReferrerBonus: 10000,
+				RefereeBonus:  5000,
+				CreatedAt:     createdAt,
+			},
+		}, nil).
+		Times(1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/referrals", nil)
+	req.Header.Set("Authorization", "Bearer authtoken")
+
+	apiTest.Router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"code": "ABCDEF1234",
+		"referrals": [
+			{"login": "friend", "status": "PENDING", "bonus": 100, "created_at": "2023-03-01T12:00:00Z"}
+		]
+	}`, w.Body.String())
+}
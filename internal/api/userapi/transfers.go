@@ -0,0 +1,51 @@
+package userapi
+
+import (
+	"encoding/json"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type transferRequest struct {
+	Recipient string      `json:"recipient" binding:"required"`
+	Sum       json.Number `json:"sum"`
+}
+
+func (api *UserAPI) transferHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	var request transferRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "wrong transfer", http.StatusBadRequest)
+		return
+	}
+
+	sum, err := domain.ParseExactMoney(request.Sum.String())
+	if err != nil {
+		reportError(c, "invalid sum", http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, err = api.transferService.Transfer(c, request.Recipient, sum, &user)
+	switch {
+	case errors.Is(err, apperrors.ErrNotEnoughMoney):
+		reportError(c, "not enough money", http.StatusPaymentRequired)
+	case errors.Is(err, apperrors.ErrRecipientNotFound):
+		reportError(c, "recipient not found", http.StatusNotFound)
+	case errors.Is(err, apperrors.ErrSelfTransfer):
+		reportError(c, "points can't be transferred to yourself", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrInvalidWithdrawSum):
+		reportError(c, "invalid sum", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrTransferLimitExceeded):
+		reportError(c, "transfer limit is exceeded", http.StatusUnprocessableEntity)
+	case err != nil:
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to transfer points")
+	default:
+		c.JSON(http.StatusOK, gin.H{"success": "OK"})
+	}
+}
@@ -0,0 +1,85 @@
+package userapi
+
+import (
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferHandler(t *testing.T) {
+	type transferCall struct {
+		sum     domain.Money
+		returns error
+	}
+	tests := []struct {
+		name         string
+		requestBody  string
+		transferCall *transferCall
+
+		wantStatus int
+	}{
+		{
+			name:        "missing recipient",
+			requestBody: `{"sum": 10}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "too precise sum",
+			requestBody: `{"recipient": "mom", "sum": 0.291}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "unknown recipient",
+			requestBody:  `{"recipient": "mom", "sum": 10}`,
+			transferCall: &transferCall{sum: 1000, returns: errors.Wrap(apperrors.ErrRecipientNotFound, "test")},
+			wantStatus:   http.StatusNotFound,
+		},
+		{
+			name:         "not enough money",
+			requestBody:  `{"recipient": "mom", "sum": 10}`,
+			transferCall: &transferCall{sum: 1000, returns: errors.Wrap(apperrors.ErrNotEnoughMoney, "test")},
+			wantStatus:   http.StatusPaymentRequired,
+		},
+		{
+			name:         "limit exceeded",
+			requestBody:  `{"recipient": "mom", "sum": 10}`,
+			transferCall: &transferCall{sum: 1000, returns: errors.Wrap(apperrors.ErrTransferLimitExceeded, "test")},
+			wantStatus:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "success case",
+			requestBody:  `{"recipient": "mom", "sum": 10.5}`,
+			transferCall: &transferCall{sum: 1050},
+			wantStatus:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.User{}
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+			if tt.transferCall != nil {
+				apiTest.TransferService.EXPECT().
+					Transfer(gomock.Any(), "mom", tt.transferCall.sum, &user).
+					Return(domain.Transfer{}, tt.transferCall.returns).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/user/balance/transfer", strings.NewReader(tt.requestBody))
+			req.Header.Set("Authorization", "Bearer authtoken")
+			req.Header.Set("Content-Type", "application/json")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package userapi
 
 import (
+	"encoding/json"
 	"fmt"
 	"gophermart/internal/core/apperrors"
 	"gophermart/internal/core/domain"
@@ -16,23 +17,42 @@ import (
 var (
 	AuthorizationHeaderName = "Authorization"
 	UserKey                 = "user"
+	TokenKey                = "token"
 )
 
 type UserAPI struct {
-	logger       zerolog.Logger
-	userService  ports.UserService
-	orderService ports.OrderService
+	logger             zerolog.Logger
+	userService        ports.UserService
+	orderService       ports.OrderService
+	holdService        ports.HoldService
+	transferService    ports.TransferService
+	tierService        ports.TierService
+	referralService    ports.ReferralService
+	promoService       ports.PromoService
+	idempotencyService ports.IdempotencyService
 }
 
 func New(
 	logService *logging.LoggerService,
 	userService ports.UserService,
 	orderService ports.OrderService,
+	holdService ports.HoldService,
+	transferService ports.TransferService,
+	tierService ports.TierService,
+	referralService ports.ReferralService,
+	promoService ports.PromoService,
+	idempotencyService ports.IdempotencyService,
 ) *UserAPI {
 	return &UserAPI{
-		logger:       logService.ComponentLogger("UserAPI"),
-		userService:  userService,
-		orderService: orderService,
+		logger:             logService.ComponentLogger("UserAPI"),
+		userService:        userService,
+		orderService:       orderService,
+		holdService:        holdService,
+		transferService:    transferService,
+		tierService:        tierService,
+		referralService:    referralService,
+		promoService:       promoService,
+		idempotencyService: idempotencyService,
 	}
 }
 
@@ -41,20 +61,36 @@ func (api *UserAPI) Register(engine *gin.Engine) {
 
 	userGroup.POST("/register", api.registerUserHandler)
 	userGroup.POST("/login", api.loginUserHandler)
-
-	userGroup.POST("/orders", api.AuthMiddleware, api.registerOrderHandler)
+	userGroup.POST("/token/refresh", api.refreshTokenHandler)
+	userGroup.POST("/logout", api.AuthMiddleware, api.logoutHandler)
+	userGroup.POST("/logout/all", api.AuthMiddleware, api.logoutAllHandler)
+	userGroup.POST("/password", api.AuthMiddleware, api.changePasswordHandler)
+	userGroup.POST("/password/reset", api.requestPasswordResetHandler)
+	userGroup.POST("/password/reset/confirm", api.resetPasswordHandler)
+
+	userGroup.POST("/orders", api.AuthMiddleware, api.IdempotencyMiddleware, api.registerOrderHandler)
 	userGroup.GET("/orders", api.AuthMiddleware, api.getOrdersHandler)
 
 	balanceGroup := userGroup.Group("/balance")
 	balanceGroup.GET("/", api.AuthMiddleware, api.balanceHandler)
-	balanceGroup.POST("/withdraw", api.AuthMiddleware, api.withdrawHandler)
+	balanceGroup.POST("/withdraw", api.AuthMiddleware, api.IdempotencyMiddleware, api.withdrawHandler)
+	balanceGroup.GET("/history", api.AuthMiddleware, api.balanceHistoryHandler)
+	balanceGroup.POST("/holds", api.AuthMiddleware, api.IdempotencyMiddleware, api.createHoldHandler)
+	balanceGroup.POST("/holds/:id/capture", api.AuthMiddleware, api.captureHoldHandler)
+	balanceGroup.POST("/holds/:id/release", api.AuthMiddleware, api.releaseHoldHandler)
+	balanceGroup.POST("/transfer", api.AuthMiddleware, api.IdempotencyMiddleware, api.transferHandler)
+	balanceGroup.GET("/tiers", api.AuthMiddleware, api.tierHistoryHandler)
 
 	userGroup.GET("/withdrawals", api.AuthMiddleware, api.withdrawalsHandler)
+	userGroup.GET("/referrals", api.AuthMiddleware, api.referralsHandler)
+	userGroup.POST("/promo/redeem", api.AuthMiddleware, api.IdempotencyMiddleware, api.redeemPromoHandler)
 }
 
 type userBody struct {
 	Login    string `json:"login" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// ReferralCode is used only at the registration
+	ReferralCode string `json:"referral_code"`
 }
 
 func (api *UserAPI) registerUserHandler(c *gin.Context) {
@@ -64,18 +100,23 @@ func (api *UserAPI) registerUserHandler(c *gin.Context) {
 		return
 	}
 
-	token, err := api.userService.RegisterUser(c, body.Login, body.Password)
+	tokens, err := api.userService.RegisterUser(c, body.Login, body.Password, body.ReferralCode)
 	if errors.Is(err, apperrors.ErrLoginIsBusy) {
 		reportError(c, "login is busy", http.StatusConflict)
 		return
+	} else if errors.Is(err, apperrors.ErrNoSuchReferralCode) {
+		reportError(c, "unknown referral code", http.StatusUnprocessableEntity)
+		return
+	} else if errors.Is(err, apperrors.ErrReferralLimitExceeded) {
+		reportError(c, "referral code can't be used anymore", http.StatusUnprocessableEntity)
+		return
 	} else if err != nil {
 		reportError(c, "server error", http.StatusInternalServerError)
 		api.logger.Error().Err(err).Msg("failed to register user")
 		return
 	}
 
-	c.Header(AuthorizationHeaderName, fmt.Sprintf("Bearer %s", token))
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	respondWithTokens(c, tokens)
 }
 
 func (api *UserAPI) loginUserHandler(c *gin.Context) {
@@ -85,7 +126,7 @@ func (api *UserAPI) loginUserHandler(c *gin.Context) {
 		return
 	}
 
-	token, err := api.userService.LoginUser(c, body.Login, body.Password)
+	tokens, err := api.userService.LoginUser(c, body.Login, body.Password)
 	if errors.Is(err, apperrors.ErrLoginOrPasswordIncorrect) {
 		reportError(c, "login or password incorrect", http.StatusUnauthorized)
 		return
@@ -95,8 +136,38 @@ func (api *UserAPI) loginUserHandler(c *gin.Context) {
 		return
 	}
 
-	c.Header(AuthorizationHeaderName, fmt.Sprintf("Bearer %s", token))
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	respondWithTokens(c, tokens)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func (api *UserAPI) refreshTokenHandler(c *gin.Context) {
+	var request refreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		reportError(c, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := api.userService.RefreshTokens(c, request.RefreshToken)
+	if errors.Is(err, apperrors.ErrInvalidRefreshToken) || errors.Is(err, apperrors.ErrRefreshTokenReused) {
+		reportError(c, "refresh token is invalid", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		reportError(c, "server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to refresh token")
+		return
+	}
+
+	respondWithTokens(c, tokens)
+}
+
+// respondWithTokens passes the access token in the header as well, the clients of the first
+// version of the API read it from there.
+func respondWithTokens(c *gin.Context, tokens domain.Tokens) {
+	c.Header(AuthorizationHeaderName, fmt.Sprintf("Bearer %s", tokens.AccessToken))
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (api *UserAPI) registerOrderHandler(c *gin.Context) {
@@ -158,15 +229,35 @@ func (api *UserAPI) balanceHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"current":   float64(balance.Current) / 100,
-		"withdrawn": float64(balance.Withdrawn) / 100,
-	})
+	c.JSON(http.StatusOK, balance)
+}
+
+func (api *UserAPI) balanceHistoryHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	entries, err := api.orderService.GetBalanceHistory(c, &user)
+	if err != nil {
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to fetch balance history")
+		return
+	}
+
+	if len(entries) == 0 {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	entriesDisplay := make([]domain.LedgerEntryDisplay, len(entries))
+	for i, entry := range entries {
+		entriesDisplay[i] = entry.ToDisplay()
+	}
+
+	c.JSON(http.StatusOK, entriesDisplay)
 }
 
 type withdrawRequest struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order string      `json:"order"`
+	Sum   json.Number `json:"sum"`
 }
 
 func (api *UserAPI) withdrawHandler(c *gin.Context) {
@@ -178,12 +269,24 @@ func (api *UserAPI) withdrawHandler(c *gin.Context) {
 		return
 	}
 
-	err := api.orderService.Withdraw(c, request.Order, int(request.Sum*100), &user)
+	sum, err := domain.ParseExactMoney(request.Sum.String())
+	if err != nil {
+		reportError(c, "invalid sum", http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = api.orderService.Withdraw(c, request.Order, sum, &user)
 	switch {
 	case errors.Is(err, apperrors.ErrNotEnoughMoney):
 		reportError(c, "not enough money", http.StatusPaymentRequired)
 	case errors.Is(err, apperrors.ErrIncorrectOrderFormat):
 		reportError(c, "incorrect order format", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrInvalidWithdrawSum):
+		reportError(c, "invalid sum", http.StatusUnprocessableEntity)
+	case errors.Is(err, apperrors.ErrWithdrawalAlreadyExists):
+		reportError(c, "withdrawal for this order already exists", http.StatusConflict)
+	case errors.Is(err, apperrors.ErrWithdrawalLimitExceeded):
+		reportError(c, "withdrawal limit of the tier is exceeded", http.StatusUnprocessableEntity)
 	case err != nil:
 		reportError(c, "internal server error", http.StatusInternalServerError)
 		api.logger.Error().Err(err).Msg("failed to fetch withdraw points")
@@ -192,6 +295,29 @@ func (api *UserAPI) withdrawHandler(c *gin.Context) {
 	}
 }
 
+func (api *UserAPI) tierHistoryHandler(c *gin.Context) {
+	user := api.GetUser(c)
+
+	changes, err := api.tierService.GetTierHistory(c, &user)
+	if err != nil {
+		reportError(c, "internal server error", http.StatusInternalServerError)
+		api.logger.Error().Err(err).Msg("failed to fetch tier history")
+		return
+	}
+
+	if len(changes) == 0 {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	changesDisplay := make([]domain.TierChangeDisplay, len(changes))
+	for i, change := range changes {
+		changesDisplay[i] = change.ToDisplay()
+	}
+
+	c.JSON(http.StatusOK, changesDisplay)
+}
+
 func (api *UserAPI) withdrawalsHandler(c *gin.Context) {
 	user := api.GetUser(c)
 
@@ -2,6 +2,7 @@ package userapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"gophermart/internal/core/apperrors"
 	"gophermart/internal/core/domain"
@@ -11,6 +12,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -48,8 +50,8 @@ func TestRegisterNewUser(t *testing.T) {
 			name:        "user already exists",
 			requestBody: makeUserBody(t, "user", "password"),
 			serviceCall: &serviceCall{
-				args:    []interface{}{gomock.Any(), "user", "password"},
-				returns: []interface{}{"", errors.Wrap(apperrors.ErrLoginIsBusy, "test error")},
+				args:    []interface{}{gomock.Any(), "user", "password", ""},
+				returns: []interface{}{domain.Tokens{}, errors.Wrap(apperrors.ErrLoginIsBusy, "test error")},
 				times:   1,
 			},
 			want: want{
@@ -61,8 +63,8 @@ func TestRegisterNewUser(t *testing.T) {
 			name:        "internal error",
 			requestBody: makeUserBody(t, "user", "password"),
 			serviceCall: &serviceCall{
-				args:    []interface{}{gomock.Any(), "user", "password"},
-				returns: []interface{}{"", errors.New("test error")},
+				args:    []interface{}{gomock.Any(), "user", "password", ""},
+				returns: []interface{}{domain.Tokens{}, errors.New("test error")},
 				times:   1,
 			},
 			want: want{
@@ -74,8 +76,34 @@ func TestRegisterNewUser(t *testing.T) {
 			name:        "success case",
 			requestBody: makeUserBody(t, "user", "password"),
 			serviceCall: &serviceCall{
-				args:    []interface{}{gomock.Any(), "user", "password"},
-				returns: []interface{}{"token", nil},
+				args:    []interface{}{gomock.Any(), "user", "password", ""},
+				returns: []interface{}{domain.Tokens{AccessToken: "token", RefreshToken: "refresh"}, nil},
+				times:   1,
+			},
+			want: want{
+				status:               http.StatusOK,
+				shouldHaveAuthHeader: true,
+			},
+		},
+		{
+			name:        "unknown referral code",
+			requestBody: []byte(`{"login": "user", "password": "password", "referral_code": "ABC"}`),
+			serviceCall: &serviceCall{
+				args:    []interface{}{gomock.Any(), "user", "password", "ABC"},
+				returns: []interface{}{domain.Tokens{}, errors.Wrap(apperrors.ErrNoSuchReferralCode, "test error")},
+				times:   1,
+			},
+			want: want{
+				status:               http.StatusUnprocessableEntity,
+				shouldHaveAuthHeader: false,
+			},
+		},
+		{
+			name:        "success case with referral code",
+			requestBody: []byte(`{"login": "user", "password": "password", "referral_code": "ABC"}`),
+			serviceCall: &serviceCall{
+				args:    []interface{}{gomock.Any(), "user", "password", "ABC"},
+				returns: []interface{}{domain.Tokens{AccessToken: "token", RefreshToken: "refresh"}, nil},
 				times:   1,
 			},
 			want: want{
@@ -90,7 +118,9 @@ func TestRegisterNewUser(t *testing.T) {
 
 			if tt.serviceCall != nil {
 				apiTest.UserService.EXPECT().
-					RegisterUser(tt.serviceCall.args[0], tt.serviceCall.args[1], tt.serviceCall.args[2]).
+					RegisterUser(
+						tt.serviceCall.args[0], tt.serviceCall.args[1], tt.serviceCall.args[2], tt.serviceCall.args[3],
+					).
 					Return(tt.serviceCall.returns[0], tt.serviceCall.returns[1]).
 					Times(tt.serviceCall.times)
 			}
@@ -147,7 +177,7 @@ func TestLoginUser(t *testing.T) {
 			requestBody: makeUserBody(t, "user", "password"),
 			serviceCall: &serviceCall{
 				args:    []interface{}{gomock.Any(), "user", "password"},
-				returns: []interface{}{"", errors.Wrap(apperrors.ErrLoginOrPasswordIncorrect, "test error")},
+				returns: []interface{}{domain.Tokens{}, errors.Wrap(apperrors.ErrLoginOrPasswordIncorrect, "test error")},
 				times:   1,
 			},
 			want: want{
@@ -160,7 +190,7 @@ func TestLoginUser(t *testing.T) {
 			requestBody: makeUserBody(t, "user", "password"),
 			serviceCall: &serviceCall{
 				args:    []interface{}{gomock.Any(), "user", "password"},
-				returns: []interface{}{"", errors.New("test error")},
+				returns: []interface{}{domain.Tokens{}, errors.New("test error")},
 				times:   1,
 			},
 			want: want{
@@ -173,7 +203,7 @@ func TestLoginUser(t *testing.T) {
 			requestBody: makeUserBody(t, "user", "password"),
 			serviceCall: &serviceCall{
 				args:    []interface{}{gomock.Any(), "user", "password"},
-				returns: []interface{}{"token", nil},
+				returns: []interface{}{domain.Tokens{AccessToken: "token", RefreshToken: "refresh"}, nil},
 				times:   1,
 			},
 			want: want{
@@ -269,6 +299,32 @@ func TestAuthMiddleware(t *testing.T) {
 				status: http.StatusUnauthorized,
 			},
 		},
+		{
+			name:        "expired token",
+			requestBody: []byte("test body"),
+			authHeader:  "Bearer authtoken",
+			authenticateUserCall: &authenticateUserCall{
+				args:    []any{gomock.Any(), "authtoken"},
+				returns: []any{domain.User{}, errors.Wrap(apperrors.ErrTokenExpired, "test error")},
+				times:   1,
+			},
+			want: want{
+				status: http.StatusUnauthorized,
+			},
+		},
+		{
+			name:        "revoked token",
+			requestBody: []byte("test body"),
+			authHeader:  "Bearer authtoken",
+			authenticateUserCall: &authenticateUserCall{
+				args:    []any{gomock.Any(), "authtoken"},
+				returns: []any{domain.User{}, errors.Wrap(apperrors.ErrTokenRevoked, "test error")},
+				times:   1,
+			},
+			want: want{
+				status: http.StatusUnauthorized,
+			},
+		},
 		{
 			name:        "unknown service error",
 			requestBody: []byte("test body"),
@@ -304,6 +360,7 @@ func TestAuthMiddleware(t *testing.T) {
 				assert.True(t, exists, "middleware should assign a user")
 				_, ok := val.(domain.User)
 				assert.True(t, ok, "middleware should assign a value of type user")
+				assert.Equal(t, "authtoken", c.GetString(TokenKey))
 				c.JSON(http.StatusOK, gin.H{"success": true})
 			})
 
@@ -396,14 +453,385 @@ func TestRegisterOrderHandler(t *testing.T) {
 	}
 }
 
+func TestWithdrawHandler(t *testing.T) {
+	type withdrawCall struct {
+		sum     domain.Money
+		returns error
+	}
+	tests := []struct {
+		name         string
+		requestBody  string
+		withdrawCall *withdrawCall
+
+		wantStatus int
+	}{
+		{
+			name:        "invalid body",
+			requestBody: `{"order": "2377225624", "sum": "abc"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "too precise sum",
+			requestBody: `{"order": "2377225624", "sum": 0.291}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "not positive sum",
+			requestBody:  `{"order": "2377225624", "sum": 0}`,
+			withdrawCall: &withdrawCall{sum: 0, returns: errors.Wrap(apperrors.ErrInvalidWithdrawSum, "test")},
+			wantStatus:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "not enough money",
+			requestBody:  `{"order": "2377225624", "sum": 751}`,
+			withdrawCall: &withdrawCall{sum: 75100, returns: errors.Wrap(apperrors.ErrNotEnoughMoney, "test")},
+			wantStatus:   http.StatusPaymentRequired,
+		},
+		{
+			name:         "incorrect order",
+			requestBody:  `{"order": "2377225624", "sum": 751}`,
+			withdrawCall: &withdrawCall{sum: 75100, returns: errors.Wrap(apperrors.ErrIncorrectOrderFormat, "test")},
+			wantStatus:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "duplicate withdrawal",
+			requestBody:  `{"order": "2377225624", "sum": 751}`,
+			withdrawCall: &withdrawCall{sum: 75100, returns: errors.Wrap(apperrors.ErrWithdrawalAlreadyExists, "test")},
+			wantStatus:   http.StatusConflict,
+		},
+		{
+			name:         "tier limit exceeded",
+			requestBody:  `{"order": "2377225624", "sum": 751}`,
+			withdrawCall: &withdrawCall{sum: 75100, returns: errors.Wrap(apperrors.ErrWithdrawalLimitExceeded, "test")},
+			wantStatus:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "success case",
+			requestBody:  `{"order": "2377225624", "sum": 0.29}`,
+			withdrawCall: &withdrawCall{sum: 29},
+			wantStatus:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.User{}
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+			if tt.withdrawCall != nil {
+				apiTest.OrderService.EXPECT().
+					Withdraw(gomock.Any(), "2377225624", tt.withdrawCall.sum, &user).
+					Return(tt.withdrawCall.returns).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/user/balance/withdraw", strings.NewReader(tt.requestBody))
+			req.Header.Set("Authorization", "Bearer authtoken")
+			req.Header.Set("Content-Type", "application/json")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestBalanceHistoryHandler(t *testing.T) {
+	createdAt := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		user        domain.User
+		entries     []domain.LedgerEntry
+		returnError error
+
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:        "internal server error",
+			returnError: errors.New("unknown error"),
+			wantStatus:  http.StatusInternalServerError,
+		},
+		{
+			name:       "empty history",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "success case",
+			entries: []domain.LedgerEntry{
+				{
+					Direction: domain.LedgerDirectionCredit,
+					Amount:    50050,
+					Reason:    domain.LedgerReasonAccrual,
+					Reference: "12345678903",
+					CreatedAt: createdAt,
+				},
+				{
+					Direction: domain.LedgerDirectionDebit,
+					Amount:    10000,
+					Reason:    domain.LedgerReasonWithdrawal,
+					Reference: "2377225624",
+					CreatedAt: createdAt,
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `[
+				{"amount": 500.5, "reason": "ACCRUAL", "reference": "12345678903", "created_at": "2023-03-01T12:00:00Z"},
+				{"amount": -100, "reason": "WITHDRAWAL", "reference": "2377225624", "created_at": "2023-03-01T12:00:00Z"}
+			]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiTest := NewAPITest(t).AuthenticateWithUser(tt.user)
+
+			apiTest.OrderService.EXPECT().
+				GetBalanceHistory(gomock.Any(), &tt.user).
+				Return(tt.entries, tt.returnError).
+				Times(1)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/user/balance/history", nil)
+			req.Header.Set("Authorization", "Bearer authtoken")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	orderNum := "12345678903"
+	storedRecord := domain.IdempotencyRecord{
+		StatusCode:  http.StatusAccepted,
+		ContentType: "application/json; charset=utf-8",
+		Body:        []byte(`{"success":"order was successfully created"}`),
+	}
+
+	tests := []struct {
+		name         string
+		beginReturns []any
+		addOrder     bool
+		addOrderErr  error
+		complete     bool
+		completeErr  error
+		abort        bool
+
+		wantStatus   int
+		wantReplayed bool
+	}{
+		{
+			name:         "first request",
+			beginReturns: []any{domain.IdempotencyRecord{UserID: 1, Key: "key"}, true, nil},
+			addOrder:     true,
+			complete:     true,
+			wantStatus:   http.StatusAccepted,
+		},
+		{
+			name:         "first request fails",
+			beginReturns: []any{domain.IdempotencyRecord{UserID: 1, Key: "key"}, true, nil},
+			addOrder:     true,
+			addOrderErr:  errors.New("unknown error"),
+			abort:        true,
+			wantStatus:   http.StatusInternalServerError,
+		},
+		{
+			name:         "response is not saved",
+			beginReturns: []any{domain.IdempotencyRecord{UserID: 1, Key: "key"}, true, nil},
+			addOrder:     true,
+			complete:     true,
+			completeErr:  errors.New("unknown error"),
+			abort:        true,
+			wantStatus:   http.StatusAccepted,
+		},
+		{
+			name:         "retry",
+			beginReturns: []any{storedRecord, false, nil},
+			wantStatus:   http.StatusAccepted,
+			wantReplayed: true,
+		},
+		{
+			name:         "request in progress",
+			beginReturns: []any{domain.IdempotencyRecord{}, false, errors.Wrap(apperrors.ErrIdempotencyKeyInProgress, "test")},
+			wantStatus:   http.StatusConflict,
+		},
+		{
+			name:         "key reused",
+			beginReturns: []any{domain.IdempotencyRecord{}, false, errors.Wrap(apperrors.ErrIdempotencyKeyReused, "test")},
+			wantStatus:   http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.User{ID: 1}
+			apiTest := NewAPITest(t).AuthenticateWithUser(user)
+
+			apiTest.IdempotencyService.EXPECT().
+				Begin(gomock.Any(), 1, "key", gomock.Any()).
+				Return(tt.beginReturns...).
+				Times(1)
+
+			if tt.addOrder {
+				apiTest.OrderService.EXPECT().
+					AddOrder(gomock.Any(), &user, orderNum).
+					Return(tt.addOrderErr).
+					Times(1)
+			}
+
+			if tt.complete {
+				apiTest.IdempotencyService.EXPECT().
+					Complete(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, record domain.IdempotencyRecord) error {
+						assert.Equal(t, http.StatusAccepted, record.StatusCode)
+						assert.JSONEq(t, string(storedRecord.Body), string(record.Body))
+						return tt.completeErr
+					}).
+					Times(1)
+			}
+
+			if tt.abort {
+				apiTest.IdempotencyService.EXPECT().
+					Abort(gomock.Any(), 1, "key").
+					Return(nil).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/user/orders", strings.NewReader(orderNum))
+			req.Header.Set("Authorization", "Bearer authtoken")
+			req.Header.Set("Idempotency-Key", "key")
+
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantReplayed {
+				assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+				assert.Equal(t, string(storedRecord.Body), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddleware_Panic(t *testing.T) {
+	user := domain.User{ID: 1}
+	apiTest := NewAPITest(t).AuthenticateWithUser(user)
+	apiTest.Router.POST(
+		"/panic",
+		gin.Recovery(),
+		apiTest.UserAPI.AuthMiddleware,
+		apiTest.UserAPI.IdempotencyMiddleware,
+		func(c *gin.Context) {
+			panic("test panic")
+		},
+	)
+
+	apiTest.IdempotencyService.EXPECT().
+		Begin(gomock.Any(), 1, "key", gomock.Any()).
+		Return(domain.IdempotencyRecord{UserID: 1, Key: "key"}, true, nil).
+		Times(1)
+	apiTest.IdempotencyService.EXPECT().
+		Abort(gomock.Any(), 1, "key").
+		Return(nil).
+		Times(1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/panic", strings.NewReader("body"))
+	req.Header.Set("Authorization", "Bearer authtoken")
+	req.Header.Set("Idempotency-Key", "key")
+
+	apiTest.Router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRefreshTokenHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestBody string
+		refreshCall bool
+		refreshErr  error
+
+		wantStatus int
+	}{
+		{
+			name:        "missing token",
+			requestBody: `{}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "invalid token",
+			requestBody: `{"refresh_token": "refresh"}`,
+			refreshCall: true,
+			refreshErr:  errors.Wrap(apperrors.ErrInvalidRefreshToken, "test error"),
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "reused token",
+			requestBody: `{"refresh_token": "refresh"}`,
+			refreshCall: true,
+			refreshErr:  errors.Wrap(apperrors.ErrRefreshTokenReused, "test error"),
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "success case",
+			requestBody: `{"refresh_token": "refresh"}`,
+			refreshCall: true,
+			wantStatus:  http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiTest := NewAPITest(t)
+
+			tokens := domain.Tokens{
+				AccessToken:  "token",
+				RefreshToken: "next",
+				ExpiresAt:    time.Date(2023, 3, 1, 12, 15, 0, 0, time.UTC),
+			}
+			if tt.refreshCall {
+				apiTest.UserService.EXPECT().
+					RefreshTokens(gomock.Any(), "refresh").
+					Return(tokens, tt.refreshErr).
+					Times(1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/user/token/refresh", strings.NewReader(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			apiTest.Router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "Bearer token", w.Header().Get("Authorization"))
+				assert.JSONEq(
+					t,
+					`{"token": "token", "refresh_token": "next", "expires_at": "2023-03-01T12:15:00Z"}`,
+					w.Body.String(),
+				)
+			}
+		})
+	}
+}
+
 // -- Test helpers --
 
 type APITest struct {
-	Router       *gin.Engine
-	UserService  *mocks.MockUserService
-	OrderService *mocks.MockOrderService
-	UserAPI      *UserAPI
-	LogService   *logging.LoggerService
+	Router             *gin.Engine
+	UserService        *mocks.MockUserService
+	OrderService       *mocks.MockOrderService
+	HoldService        *mocks.MockHoldService
+	TransferService    *mocks.MockTransferService
+	TierService        *mocks.MockTierService
+	ReferralService    *mocks.MockReferralService
+	PromoService       *mocks.MockPromoService
+	IdempotencyService *mocks.MockIdempotencyService
+	UserAPI            *UserAPI
+	LogService         *logging.LoggerService
 }
 
 func NewAPITest(t *testing.T) *APITest {
@@ -412,16 +840,38 @@ func NewAPITest(t *testing.T) *APITest {
 	router := gin.New()
 	logService := logging.New()
 	orderService := mocks.NewMockOrderService(ctrl)
-	userAPI := New(logService, userService, orderService)
+	holdService := mocks.NewMockHoldService(ctrl)
+	transferService := mocks.NewMockTransferService(ctrl)
+	tierService := mocks.NewMockTierService(ctrl)
+	referralService := mocks.NewMockReferralService(ctrl)
+	promoService := mocks.NewMockPromoService(ctrl)
+	idempotencyService := mocks.NewMockIdempotencyService(ctrl)
+	userAPI := New(
+		logService,
+		userService,
+		orderService,
+		holdService,
+		transferService,
+		tierService,
+		referralService,
+		promoService,
+		idempotencyService,
+	)
 
 	userAPI.Register(router)
 
 	return &APITest{
-		Router:       router,
-		UserService:  userService,
-		OrderService: orderService,
-		UserAPI:      userAPI,
-		LogService:   logService,
+		Router:             router,
+		UserService:        userService,
+		OrderService:       orderService,
+		HoldService:        holdService,
+		TransferService:    transferService,
+		TierService:        tierService,
+		ReferralService:    referralService,
+		PromoService:       promoService,
+		IdempotencyService: idempotencyService,
+		UserAPI:            userAPI,
+		LogService:         logService,
 	}
 }
 
@@ -1,18 +1,74 @@
 package apperrors
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 var (
 	ErrLoginIsBusy              = errors.New("login is busy")
 	ErrLoginIsEmpty             = errors.New("login is empty")
 	ErrPasswordIsEmpty          = errors.New("password is empty")
 	ErrLoginOrPasswordIncorrect = errors.New("login or password incorrect")
+	ErrPasswordIncorrect        = errors.New("password is incorrect")
 	ErrAuthFailed               = errors.New("authentication has failed")
+	ErrTokenExpired             = errors.New("access token is expired")
+	ErrTokenRevoked             = errors.New("access token is revoked")
+	ErrInvalidRefreshToken      = errors.New("refresh token is invalid, expired or revoked")
+	ErrRefreshTokenReused       = errors.New("refresh token is already used")
+	ErrInvalidResetToken        = errors.New("password reset token is invalid, expired or used")
+	ErrPasswordResetDisabled    = errors.New("password reset is disabled")
 
 	ErrOrderWasPostedByThisUser    = errors.New("the order with such number was already posted by this user")
 	ErrOrderWasPostedByAnotherUser = errors.New("the order with such number was already posted by another user")
 	ErrIncorrectOrderFormat        = errors.New("incorrect order format")
 	ErrNoSuchOrder                 = errors.New("no such order in the database")
 
-	ErrNotEnoughMoney = errors.New("not enough money")
+	ErrInvalidMoney = errors.New("invalid amount of money")
+
+	ErrNotEnoughMoney          = errors.New("not enough money")
+	ErrInvalidWithdrawSum      = errors.New("withdraw sum should be a positive number of points with at most two fractional digits")
+	ErrWithdrawalAlreadyExists = errors.New("withdrawal for the order already exists")
+	ErrNoSuchWithdrawal        = errors.New("no such withdrawal in the database")
+	ErrWithdrawalReversed      = errors.New("withdrawal is already reversed")
+	ErrReversalReasonIsEmpty   = errors.New("reason of the reversal is empty")
+	ErrWithdrawalLimitExceeded = errors.New("withdrawal limit of the tier is exceeded")
+
+	ErrRecipientNotFound     = errors.New("recipient of the transfer is not found")
+	ErrSelfTransfer          = errors.New("points can't be transferred to yourself")
+	ErrTransferLimitExceeded = errors.New("transfer limit is exceeded")
+
+	ErrNoSuchReferralCode    = errors.New("no such referral code")
+	ErrSelfReferral          = errors.New("users can't invite themselves")
+	ErrReferralLimitExceeded = errors.New("referral limit of the user is exceeded")
+	ErrAlreadyReferred       = errors.New("user is already invited")
+
+	ErrNoSuchPromoCode        = errors.New("no such promo code")
+	ErrInvalidPromoCode       = errors.New("promo code should have a code, a positive amount and a valid window")
+	ErrPromoCodeAlreadyExists = errors.New("promo code already exists")
+	ErrPromoCodeNotValid      = errors.New("promo code is not valid at the moment")
+	ErrPromoCodeExhausted     = errors.New("promo code is redeemed the maximum number of times")
+	ErrPromoCodeAlreadyUsed   = errors.New("promo code is already redeemed by the user")
+
+	ErrNoSuchHold        = errors.New("no such hold in the database")
+	ErrHoldAlreadyExists = errors.New("hold for the order already exists")
+	ErrHoldNotActive     = errors.New("hold is already captured, released or expired")
+
+	ErrIdempotencyKeyInProgress = errors.New("request with such idempotency key is in progress")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used for another request")
+
+	ErrUnknownAccrualStatus = errors.New("unknown accrual status")
+	ErrCircuitOpen          = errors.New("accrual system circuit breaker is open")
 )
+
+// RateLimitError is returned when the accrual system answers with 429 Too Many Requests.
+// RetryAfter tells how long the caller should wait before sending the next request.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("accrual system rate limit exceeded, retry after %s", e.RetryAfter)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"gophermart/internal/core/apperrors"
+
+	"github.com/pkg/errors"
+)
+
+// AccrualStatus is a status of the order calculation reported by the accrual system.
+type AccrualStatus string
+
+const (
+	// AccrualStatusNotRegistered is not sent by the accrual system, it is used when
+	// the accrual system answers with 204, meaning it doesn't know the order yet.
+	AccrualStatusNotRegistered AccrualStatus = "NOT_REGISTERED"
+	AccrualStatusRegistered    AccrualStatus = "REGISTERED"
+	AccrualStatusProcessing    AccrualStatus = "PROCESSING"
+	AccrualStatusInvalid       AccrualStatus = "INVALID"
+	AccrualStatusProcessed     AccrualStatus = "PROCESSED"
+)
+
+// ToOrderStatus maps the status of the accrual system to the status of the order.
+func (s AccrualStatus) ToOrderStatus() (OrderStatus, error) {
+	switch s {
+	case AccrualStatusNotRegistered, AccrualStatusRegistered:
+		return OrderStatusNew, nil
+	case AccrualStatusProcessing:
+		return OrderStatusProcessing, nil
+	case AccrualStatusInvalid:
+		return OrderStatusInvalid, nil
+	case AccrualStatusProcessed:
+		return OrderStatusProcessed, nil
+	default:
+		return "", errors.Wrapf(apperrors.ErrUnknownAccrualStatus, "accrual status '%s'", s)
+	}
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"time"
+)
+
+// ExpiryPolicy tells when the earned points expire.
+type ExpiryPolicy struct {
+	// Months is the lifetime of the points, 0 means they never expire.
+	Months int
+	// Notice is how long before the expiration the points are shown as expiring soon.
+	Notice time.Duration
+}
+
+func (p ExpiryPolicy) Enabled() bool {
+	return p.Months > 0
+}
+
+// ExpiresAt returns the moment when the points earned at the given time expire.
+func (p ExpiryPolicy) ExpiresAt(earnedAt time.Time) time.Time {
+	return earnedAt.AddDate(0, p.Months, 0)
+}
+
+// EarnedBefore returns the moment before which the earned points have already expired.
+func (p ExpiryPolicy) EarnedBefore(now time.Time) time.Time {
+	return now.AddDate(0, -p.Months, 0)
+}
+
+// PointsLot is the part of the points earned by a single posting which is not spent yet.
+type PointsLot struct {
+	ID            int       `db:"id"`
+	TransactionID string    `db:"transaction_id"`
+	EarnedAt      time.Time `db:"earned_at"`
+	// Amount is what remains of the lot
+	Amount Money `db:"remaining"`
+	// Expirations is how many times the points of the lot have already expired. The points
+	// released from a hold return to their lot, so the same lot may expire more than once.
+	Expirations int `db:"expirations"`
+}
+
+type ExpiringPoints struct {
+	Amount    Money     `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExpiredLots returns the lots which have expired by now.
+func (p ExpiryPolicy) ExpiredLots(lots []PointsLot, now time.Time) []PointsLot {
+	if !p.Enabled() {
+		return nil
+	}
+
+	var expired []PointsLot
+	for _, lot := range lots {
+		if !p.ExpiresAt(lot.EarnedAt).After(now) {
+			expired = append(expired, lot)
+		}
+	}
+
+	return expired
+}
+
+// ExpiringSoon returns the points of the lots which expire within the notice period.
+func (p ExpiryPolicy) ExpiringSoon(lots []PointsLot, now time.Time) []ExpiringPoints {
+	if !p.Enabled() {
+		return nil
+	}
+
+	var expiring []ExpiringPoints
+	for _, lot := range lots {
+		expiresAt := p.ExpiresAt(lot.EarnedAt)
+		if expiresAt.After(now) && !expiresAt.After(now.Add(p.Notice)) {
+			expiring = append(expiring, ExpiringPoints{Amount: lot.Amount, ExpiresAt: expiresAt})
+		}
+	}
+
+	return expiring
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryPolicy(t *testing.T) {
+	policy := ExpiryPolicy{Months: 6, Notice: 30 * 24 * time.Hour}
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	lots := []PointsLot{
+		{ID: 1, TransactionID: "accrual:1", EarnedAt: time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC), Amount: 500},
+		{ID: 2, TransactionID: "accrual:2", EarnedAt: time.Date(2022, 9, 15, 0, 0, 0, 0, time.UTC), Amount: 1500},
+		{ID: 3, TransactionID: "accrual:3", EarnedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Amount: 3000},
+	}
+
+	assert.Equal(t, []PointsLot{lots[0]}, policy.ExpiredLots(lots, now))
+	assert.Equal(t, []ExpiringPoints{
+		{Amount: 1500, ExpiresAt: time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC)},
+	}, policy.ExpiringSoon(lots, now))
+
+	later := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []PointsLot{lots[0], lots[1]}, policy.ExpiredLots(lots, later))
+	assert.Empty(t, policy.ExpiringSoon(lots, later))
+
+	assert.Empty(t, ExpiryPolicy{}.ExpiredLots(lots, later))
+	assert.Empty(t, ExpiryPolicy{}.ExpiringSoon(lots, later))
+}
+
+func TestExpiryTransaction(t *testing.T) {
+	lot := PointsLot{ID: 7, TransactionID: "accrual:1", Amount: 500}
+	at := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, LedgerTransaction{
+		ID:        "expiry:7:0",
+		UserID:    1,
+		From:      LedgerAccountPoints,
+		To:        LedgerAccountExpired,
+		Amount:    500,
+		Reason:    LedgerReasonExpiration,
+		Reference: "accrual:1",
+		CreatedAt: at,
+	}, ExpiryTransaction(1, lot, at))
+
+	// The points released into the expired lot burn under a new ID
+	lot.Expirations++
+	assert.Equal(t, "expiry:7:1", ExpiryTransaction(1, lot, at).ID)
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+type HoldStatus string
+
+const (
+	// HoldStatusActive means the points are reserved and can't be spent otherwise.
+	HoldStatusActive HoldStatus = "ACTIVE"
+	// HoldStatusCaptured means the reserved points are withdrawn.
+	HoldStatusCaptured HoldStatus = "CAPTURED"
+	// HoldStatusReleased means the reserved points are given back to the user.
+	HoldStatusReleased HoldStatus = "RELEASED"
+	// HoldStatusExpired means the hold wasn't captured in time and the points are given back.
+	HoldStatusExpired HoldStatus = "EXPIRED"
+)
+
+// Hold reserves the points while the shop order is being paid.
+type Hold struct {
+	ID          int        `db:"id"`
+	UserID      int        `db:"user_id"`
+	OrderNumber string     `db:"order_number"`
+	Amount      Money      `db:"amount"`
+	Status      HoldStatus `db:"status"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	FinishedAt  *time.Time `db:"finished_at"`
+}
+
+// IsActive reports whether the hold can still be captured or released.
+func (h Hold) IsActive(now time.Time) bool {
+	return h.Status == HoldStatusActive && now.Before(h.ExpiresAt)
+}
+
+type HoldDisplay struct {
+	ID          int        `json:"id"`
+	OrderNumber string     `json:"order"`
+	Amount      Money      `json:"sum"`
+	Status      HoldStatus `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+func (h Hold) ToDisplay() HoldDisplay {
+	return HoldDisplay{
+		ID:          h.ID,
+		OrderNumber: h.OrderNumber,
+		Amount:      h.Amount,
+		Status:      h.Status,
+		CreatedAt:   h.CreatedAt,
+		ExpiresAt:   h.ExpiresAt,
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord keeps the response to the request made with an idempotency key,
+// so the retries of the same request get the same response.
+type IdempotencyRecord struct {
+	UserID      int       `db:"user_id"`
+	Key         string    `db:"key"`
+	RequestHash string    `db:"request_hash"`
+	StatusCode  int       `db:"status_code"`
+	ContentType string    `db:"content_type"`
+	Body        []byte    `db:"response_body"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+// InProgress reports whether the first request with the key hasn't finished yet.
+func (r IdempotencyRecord) InProgress() bool {
+	return r.StatusCode == 0
+}
@@ -0,0 +1,302 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// LedgerAccount is an account of the user in the double-entry ledger.
+// Every transaction moves points from one account to another.
+type LedgerAccount string
+
+const (
+	// LedgerAccountPoints keeps the points the user can spend.
+	LedgerAccountPoints LedgerAccount = "POINTS"
+	// LedgerAccountAccruals is the source of the points accrued for orders.
+	LedgerAccountAccruals LedgerAccount = "ACCRUALS"
+	// LedgerAccountWithdrawals collects the points the user has spent.
+	LedgerAccountWithdrawals LedgerAccount = "WITHDRAWALS"
+	// LedgerAccountExpired collects the points which weren't spent in time.
+	LedgerAccountExpired LedgerAccount = "EXPIRED"
+	// LedgerAccountHeld keeps the points reserved by the active holds.
+	LedgerAccountHeld LedgerAccount = "HELD"
+	// LedgerAccountBonuses is the source of the points granted by the service itself.
+	LedgerAccountBonuses LedgerAccount = "BONUSES"
+)
+
+type LedgerDirection string
+
+const (
+	LedgerDirectionDebit  LedgerDirection = "DEBIT"
+	LedgerDirectionCredit LedgerDirection = "CREDIT"
+)
+
+// LedgerReason explains why the balance has changed.
+type LedgerReason string
+
+const (
+	LedgerReasonAccrual    LedgerReason = "ACCRUAL"
+	LedgerReasonWithdrawal LedgerReason = "WITHDRAWAL"
+	LedgerReasonReversal   LedgerReason = "WITHDRAWAL_REVERSAL"
+	LedgerReasonExpiration LedgerReason = "EXPIRATION"
+	LedgerReasonHold       LedgerReason = "HOLD"
+	LedgerReasonRelease    LedgerReason = "HOLD_RELEASE"
+	LedgerReasonTransfer   LedgerReason = "TRANSFER"
+	LedgerReasonSignup     LedgerReason = "SIGNUP_BONUS"
+	LedgerReasonReferral   LedgerReason = "REFERRAL_BONUS"
+	LedgerReasonPromo      LedgerReason = "PROMO_CODE"
+	// LedgerReasonWriteOff is posted by the migration which cleared the balances overdrawn
+	// by the concurrent withdrawals, see balance_overdrafts.
+	LedgerReasonWriteOff LedgerReason = "OVERDRAFT_WRITE_OFF"
+)
+
+// LedgerEntry is an immutable posting to one of the accounts.
+type LedgerEntry struct {
+	ID            int             `db:"id"`
+	TransactionID string          `db:"transaction_id"`
+	UserID        int             `db:"user_id"`
+	Account       LedgerAccount   `db:"account"`
+	Direction     LedgerDirection `db:"direction"`
+	Amount        Money           `db:"amount"`
+	Reason        LedgerReason    `db:"reason"`
+	Reference     string          `db:"reference"`
+	CreatedAt     time.Time       `db:"created_at"`
+}
+
+// SignedAmount is positive for the entries increasing the account and negative otherwise.
+func (e LedgerEntry) SignedAmount() Money {
+	if e.Direction == LedgerDirectionDebit {
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+type LedgerEntryDisplay struct {
+	Amount    Money        `json:"amount"`
+	Reason    LedgerReason `json:"reason"`
+	Reference string       `json:"reference,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func (e LedgerEntry) ToDisplay() LedgerEntryDisplay {
+	return LedgerEntryDisplay{
+		Amount:    e.SignedAmount(),
+		Reason:    e.Reason,
+		Reference: e.Reference,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// LedgerTransaction moves the amount of points from one account of the user to another.
+// The transaction ID is unique, so the same transaction is never posted twice.
+type LedgerTransaction struct {
+	ID     string
+	UserID int
+	// ToUserID is the owner of the To account when it's not the same user, e.g. for transfers
+	ToUserID  int
+	From      LedgerAccount
+	To        LedgerAccount
+	Amount    Money
+	Reason    LedgerReason
+	Reference string
+	CreatedAt time.Time
+	// Returns is the transaction whose spent points come back, they keep the age of their lots
+	Returns string
+}
+
+// Entries returns the balanced pair of postings of the transaction.
+func (t LedgerTransaction) Entries() []LedgerEntry {
+	entry := LedgerEntry{
+		TransactionID: t.ID,
+		UserID:        t.UserID,
+		Amount:        t.Amount,
+		Reason:        t.Reason,
+		Reference:     t.Reference,
+		CreatedAt:     t.CreatedAt,
+	}
+
+	debit, credit := entry, entry
+	debit.Account, debit.Direction = t.From, LedgerDirectionDebit
+	credit.Account, credit.Direction = t.To, LedgerDirectionCredit
+	if t.ToUserID != 0 {
+		credit.UserID = t.ToUserID
+	}
+
+	return []LedgerEntry{debit, credit}
+}
+
+// AccrualTransaction credits the points accrued for the processed order.
+func AccrualTransaction(order Order, at time.Time) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("accrual:%s", order.OrderNumber),
+		UserID:    order.UserID,
+		From:      LedgerAccountAccruals,
+		To:        LedgerAccountPoints,
+		Amount:    order.Accrual,
+		Reason:    LedgerReasonAccrual,
+		Reference: order.OrderNumber,
+		CreatedAt: at,
+	}
+}
+
+// WithdrawalTransaction debits the points spent on the order.
+func WithdrawalTransaction(withdrawn Withdrawn) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("withdrawal:%d", withdrawn.ID),
+		UserID:    withdrawn.UserID,
+		From:      LedgerAccountPoints,
+		To:        LedgerAccountWithdrawals,
+		Amount:    withdrawn.Sum,
+		Reason:    LedgerReasonWithdrawal,
+		Reference: withdrawn.OrderNumber,
+		CreatedAt: withdrawn.ProcessedAt,
+	}
+}
+
+// ReversalTransaction gives the points of the reversed withdrawn back to the user. The points return
+// to the lots they were spent from by the given transaction: the withdrawal itself or the captured hold.
+func ReversalTransaction(withdrawn Withdrawn, spentBy string, at time.Time) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("reversal:%d", withdrawn.ID),
+		UserID:    withdrawn.UserID,
+		From:      LedgerAccountWithdrawals,
+		To:        LedgerAccountPoints,
+		Amount:    withdrawn.Sum,
+		Reason:    LedgerReasonReversal,
+		Reference: withdrawn.OrderNumber,
+		CreatedAt: at,
+		Returns:   spentBy,
+	}
+}
+
+// ExpiryTransaction burns the remaining points of the expired lot. Every expiration of the lot
+// gets its own ID, otherwise the points released into the lot after it expired would never burn.
+func ExpiryTransaction(userID int, lot PointsLot, at time.Time) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("expiry:%d:%d", lot.ID, lot.Expirations),
+		UserID:    userID,
+		From:      LedgerAccountPoints,
+		To:        LedgerAccountExpired,
+		Amount:    lot.Amount,
+		Reason:    LedgerReasonExpiration,
+		Reference: lot.TransactionID,
+		CreatedAt: at,
+	}
+}
+
+// HoldTransaction reserves the points of the hold.
+func HoldTransaction(hold Hold) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("hold:%d", hold.ID),
+		UserID:    hold.UserID,
+		From:      LedgerAccountPoints,
+		To:        LedgerAccountHeld,
+		Amount:    hold.Amount,
+		Reason:    LedgerReasonHold,
+		Reference: hold.OrderNumber,
+		CreatedAt: hold.CreatedAt,
+	}
+}
+
+// CaptureTransaction withdraws the points reserved by the hold.
+func CaptureTransaction(withdrawn Withdrawn) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("withdrawal:%d", withdrawn.ID),
+		UserID:    withdrawn.UserID,
+		From:      LedgerAccountHeld,
+		To:        LedgerAccountWithdrawals,
+		Amount:    withdrawn.Sum,
+		Reason:    LedgerReasonWithdrawal,
+		Reference: withdrawn.OrderNumber,
+		CreatedAt: withdrawn.ProcessedAt,
+	}
+}
+
+// ReleaseTransaction gives the points reserved by the released or expired hold back.
+func ReleaseTransaction(hold Hold, at time.Time) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("release:%d", hold.ID),
+		UserID:    hold.UserID,
+		From:      LedgerAccountHeld,
+		To:        LedgerAccountPoints,
+		Amount:    hold.Amount,
+		Reason:    LedgerReasonRelease,
+		Reference: hold.OrderNumber,
+		CreatedAt: at,
+		Returns:   HoldTransaction(hold).ID,
+	}
+}
+
+// TransferTransaction moves the points from the sender to the recipient.
+func TransferTransaction(transfer Transfer) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("transfer:%d", transfer.ID),
+		UserID:    transfer.SenderID,
+		ToUserID:  transfer.RecipientID,
+		From:      LedgerAccountPoints,
+		To:        LedgerAccountPoints,
+		Amount:    transfer.Amount,
+		Reason:    LedgerReasonTransfer,
+		Reference: fmt.Sprintf("%d", transfer.ID),
+		CreatedAt: transfer.CreatedAt,
+	}
+}
+
+// SignupBonusTransaction credits the bonus for the registration, every user gets it only once.
+func SignupBonusTransaction(userID int, amount Money, rule string, at time.Time) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("signup:%d", userID),
+		UserID:    userID,
+		From:      LedgerAccountBonuses,
+		To:        LedgerAccountPoints,
+		Amount:    amount,
+		Reason:    LedgerReasonSignup,
+		Reference: rule,
+		CreatedAt: at,
+	}
+}
+
+func PromoTransaction(redemption PromoRedemption) LedgerTransaction {
+	return LedgerTransaction{
+		ID:        fmt.Sprintf("promo:%d", redemption.ID),
+		UserID:    redemption.UserID,
+		From:      LedgerAccountBonuses,
+		To:        LedgerAccountPoints,
+		Amount:    redemption.Amount,
+		Reason:    LedgerReasonPromo,
+		Reference: redemption.Code,
+		CreatedAt: redemption.RedeemedAt,
+	}
+}
+
+// ReferralTransactions grant the bonuses of the referral to both users.
+// The bonuses equal to 0 are not posted.
+func ReferralTransactions(referral Referral, at time.Time) []LedgerTransaction {
+	bonuses := []struct {
+		userID int
+		amount Money
+	}{
+		{referral.ReferrerID, referral.ReferrerBonus},
+		{referral.RefereeID, referral.RefereeBonus},
+	}
+
+	var transactions []LedgerTransaction
+	for _, bonus := range bonuses {
+		if bonus.amount <= 0 {
+			continue
+		}
+
+		transactions = append(transactions, LedgerTransaction{
+			ID:        fmt.Sprintf("referral:%d", referral.ID),
+			UserID:    bonus.userID,
+			From:      LedgerAccountBonuses,
+			To:        LedgerAccountPoints,
+			Amount:    bonus.amount,
+			Reason:    LedgerReasonReferral,
+			Reference: referral.OrderNumber,
+			CreatedAt: at,
+		})
+	}
+
+	return transactions
+}
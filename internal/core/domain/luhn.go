@@ -1,19 +1,19 @@
-package orderservice
+package domain
 
 import (
 	"strconv"
 )
 
-// Valid check number is valid or not based on Luhn algorithm
-func luhnValid(number string) bool {
+// ValidOrderNumber checks whether the order number is valid based on Luhn algorithm
+func ValidOrderNumber(number string) bool {
 	intNumber, err := strconv.Atoi(number)
 	if err != nil {
 		return false
 	}
-	return (intNumber%10+checksum(intNumber/10))%10 == 0
+	return (intNumber%10+luhnChecksum(intNumber/10))%10 == 0
 }
 
-func checksum(number int) int {
+func luhnChecksum(number int) int {
 	var luhn int
 
 	for i := 0; number > 0; i++ {
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"gophermart/internal/core/apperrors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Money is an amount of loyalty points, 1 point = 1 ruble. It's kept in hundredths, so there
+// is no floating point anywhere between the JSON of the API and the database.
+//
+// The amounts coming from the outside must be exact: JSON numbers with more than two fractional
+// digits are rejected. The calculated amounts are rounded half away from zero, it's the only
+// rounding policy used in the service.
+type Money int64
+
+var (
+	hundred = big.NewInt(100)
+	two     = big.NewInt(2)
+)
+
+// ParseMoney parses a decimal number, e.g. "500.5" or "5e2", rounding it to hundredths.
+func ParseMoney(s string) (Money, error) {
+	hundredths, err := parseHundredths(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return roundHundredths(s, hundredths)
+}
+
+// ParseExactMoney parses a decimal number and fails if it can't be represented in hundredths.
+func ParseExactMoney(s string) (Money, error) {
+	hundredths, err := parseHundredths(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if !hundredths.IsInt() {
+		return 0, errors.Wrapf(apperrors.ErrInvalidMoney, "'%s' has more than two fractional digits", s)
+	}
+
+	return roundHundredths(s, hundredths)
+}
+
+func parseHundredths(s string) (*big.Rat, error) {
+	// big.Rat also understands fractions like 1/3 which are not decimal numbers
+	if strings.Contains(s, "/") {
+		return nil, errors.Wrapf(apperrors.ErrInvalidMoney, "'%s' is not a decimal number", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, errors.Wrapf(apperrors.ErrInvalidMoney, "'%s' is not a decimal number", s)
+	}
+
+	return r.Mul(r, new(big.Rat).SetInt(hundred)), nil
+}
+
+func roundHundredths(s string, r *big.Rat) (Money, error) {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+
+	// Half away from zero
+	if new(big.Int).Mul(rem.Abs(rem), two).Cmp(r.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(int64(r.Sign())))
+	}
+
+	if !quo.IsInt64() {
+		return 0, errors.Wrapf(apperrors.ErrInvalidMoney, "'%s' is too big", s)
+	}
+
+	return Money(quo.Int64()), nil
+}
+
+// Multiply multiplies the amount by the exact factor, the result is rounded to hundredths.
+func (m Money) Multiply(factor *big.Rat) (Money, error) {
+	r := new(big.Rat).SetInt64(int64(m))
+	r.Mul(r, factor)
+	return roundHundredths(m.String(), r)
+}
+
+// String formats the amount as a decimal number without trailing zeros, e.g. "500.5".
+func (m Money) String() string {
+	sign := ""
+	abs := int64(m)
+	if abs < 0 {
+		sign, abs = "-", -abs
+	}
+
+	whole, fraction := abs/100, abs%100
+	switch {
+	case fraction == 0:
+		return fmt.Sprintf("%s%d", sign, whole)
+	case fraction%10 == 0:
+		return fmt.Sprintf("%s%d.%d", sign, whole, fraction/10)
+	default:
+		return fmt.Sprintf("%s%d.%02d", sign, whole, fraction)
+	}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON parses the exact amount, which must be a JSON number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		return errors.Wrapf(apperrors.ErrInvalidMoney, "%s is not a number", s)
+	}
+
+	parsed, err := ParseExactMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// UnmarshalText parses the exact amount, e.g. from the configuration.
+func (m *Money) UnmarshalText(text []byte) error {
+	parsed, err := ParseExactMoney(string(text))
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Scan reads hundredths stored in the database.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return errors.Errorf("can't scan %T into money", src)
+	}
+
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "can't scan '%s' into money", s)
+	}
+
+	*m = Money(v)
+	return nil
+}
+
+// Value stores hundredths in the database.
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
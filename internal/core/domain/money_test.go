@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"encoding/json"
+	"gophermart/internal/core/apperrors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		number  string
+		want    Money
+		wantErr bool
+	}{
+		{number: "0.29", want: 29},
+		{number: "751", want: 75100},
+		{number: "1.5", want: 150},
+		{number: "5e2", want: 50000},
+		{number: "0.125", want: 13},
+		{number: "0.124", want: 12},
+		{number: "-0.125", want: -13},
+		{number: "abc", wantErr: true},
+		{number: "1/3", wantErr: true},
+		{number: "999999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			money, err := ParseMoney(tt.number)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, apperrors.ErrInvalidMoney)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, money)
+		})
+	}
+}
+
+func TestParseExactMoney(t *testing.T) {
+	tests := []struct {
+		number  string
+		want    Money
+		wantErr bool
+	}{
+		{number: "0.29", want: 29},
+		{number: "100.05", want: 10005},
+		{number: "-2.1", want: -210},
+		{number: "1e2", want: 10000},
+		{number: "0", want: 0},
+		{number: "0.291", wantErr: true},
+		{number: "1e-3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			money, err := ParseExactMoney(tt.number)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, apperrors.ErrInvalidMoney)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, money)
+		})
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	tests := []struct {
+		money Money
+		json  string
+	}{
+		{money: 0, json: "0"},
+		{money: 50050, json: "500.5"},
+		{money: 29, json: "0.29"},
+		{money: 75100, json: "751"},
+		{money: -5, json: "-0.05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			data, err := json.Marshal(tt.money)
+			require.NoError(t, err)
+			assert.Equal(t, tt.json, string(data))
+
+			var money Money
+			require.NoError(t, json.Unmarshal(data, &money))
+			assert.Equal(t, tt.money, money)
+		})
+	}
+
+	// The same amounts are rejected in any JSON input
+	for _, invalid := range []string{`"500.5"`, `0.291`, `"abc"`} {
+		t.Run(invalid, func(t *testing.T) {
+			var money Money
+			err := json.Unmarshal([]byte(invalid), &money)
+			assert.ErrorIs(t, err, apperrors.ErrInvalidMoney)
+		})
+	}
+}
+
+func TestMoney_Scan(t *testing.T) {
+	var money Money
+	require.NoError(t, money.Scan(int64(50050)))
+	assert.Equal(t, Money(50050), money)
+
+	require.NoError(t, money.Scan([]byte("29")))
+	assert.Equal(t, Money(29), money)
+
+	assert.Error(t, money.Scan(1.5))
+}
@@ -0,0 +1,9 @@
+package domain
+
+// Notification is a message sent to the user outside of the API.
+// The users have no contact addresses, so the notification is addressed by the login.
+type Notification struct {
+	Login   string
+	Subject string
+	Body    string
+}
@@ -11,20 +11,37 @@ const (
 	OrderStatusProcessed  OrderStatus = "PROCESSED"
 )
 
+// IsFinal reports whether the status can't be changed anymore.
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderStatusInvalid || s == OrderStatusProcessed
+}
+
 type Order struct {
 	ID          int         `db:"id" json:"-"`
 	UserID      int         `db:"user_id" json:"-"`
 	OrderNumber string      `db:"order_number" json:"number"`
 	Status      OrderStatus `db:"status" json:"status"`
-	Accrual     int         `db:"accrual" json:"accrual,omitempty"`
+	Accrual     Money       `db:"accrual" json:"accrual,omitempty"`
 	CreatedAt   time.Time   `db:"created_at" json:"uploaded_at"`
 	UpdatedAt   time.Time   `db:"updated_at" json:"-"`
+
+	// Schedule of checks in the accrual system
+	NextCheckAt time.Time `db:"next_check_at" json:"-"`
+	Attempts    int       `db:"attempts" json:"-"`
+	NeedsReview bool      `db:"needs_review" json:"-"`
+
+	// BaseAccrual is the accrual received from the accrual system before the local rules are applied
+	BaseAccrual  Money        `db:"base_accrual" json:"-"`
+	AppliedRules AppliedRules `db:"applied_rules" json:"-"`
+
+	// LockedUntil is a lease of the accrual worker instance which checks the order right now
+	LockedUntil *time.Time `db:"locked_until" json:"-"`
 }
 
 type OrderDisplay struct {
 	OrderNumber string      `json:"number"`
 	Status      OrderStatus `json:"status"`
-	Accrual     float64     `json:"accrual,omitempty"`
+	Accrual     Money       `json:"accrual,omitempty"`
 	CreatedAt   time.Time   `json:"uploaded_at"`
 }
 
@@ -32,12 +49,46 @@ func (o Order) ToDisplay() OrderDisplay {
 	return OrderDisplay{
 		OrderNumber: o.OrderNumber,
 		Status:      o.Status,
-		Accrual:     float64(o.Accrual) / 100,
+		Accrual:     o.Accrual,
 		CreatedAt:   time.Time{},
 	}
 }
 
+// OrderAudit explains the accrual of the order to the support.
+type OrderAudit struct {
+	OrderNumber  string       `json:"number"`
+	UserID       int          `json:"user_id"`
+	Status       OrderStatus  `json:"status"`
+	BaseAccrual  Money        `json:"base_accrual"`
+	Accrual      Money        `json:"accrual"`
+	AppliedRules AppliedRules `json:"applied_rules"`
+	CreatedAt    time.Time    `json:"uploaded_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+func (o Order) ToAudit() OrderAudit {
+	return OrderAudit{
+		OrderNumber:  o.OrderNumber,
+		UserID:       o.UserID,
+		Status:       o.Status,
+		BaseAccrual:  o.BaseAccrual,
+		Accrual:      o.Accrual,
+		AppliedRules: o.AppliedRules,
+		CreatedAt:    o.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+	}
+}
+
 type UserBalance struct {
-	Current   int `json:"current"`
-	Withdrawn int `json:"withdrawn"`
+	Current   Money `db:"current" json:"current"`
+	Held      Money `db:"held" json:"held"`
+	Withdrawn Money `db:"withdrawn" json:"withdrawn"`
+
+	// ExpiringSoon is calculated from the unspent lots, it's not stored in the balance row
+	ExpiringSoon []ExpiringPoints `db:"-" json:"expiring_soon,omitempty"`
+
+	// Tier of the user and its benefits, empty when there are no tiers
+	Tier             TierName          `db:"-" json:"tier,omitempty"`
+	TierMultiplier   string            `db:"-" json:"tier_multiplier,omitempty"`
+	WithdrawalLimits *WithdrawalLimits `db:"-" json:"withdrawal_limits,omitempty"`
 }
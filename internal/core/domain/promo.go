@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// PromoCode grants a fixed amount of points to the users who redeem it.
+type PromoCode struct {
+	ID     int    `db:"id"`
+	Code   string `db:"code"`
+	Amount Money  `db:"amount"`
+	// MaxRedemptions is the limit of all the redemptions of the code, 0 means no limit.
+	MaxRedemptions int `db:"max_redemptions"`
+	// MaxPerUser is the limit of the redemptions of the code by a single user, 0 means no limit.
+	MaxPerUser  int `db:"max_per_user"`
+	Redemptions int `db:"redemptions"`
+	// The code can be redeemed within [ValidFrom, ValidTo), nil means the window is open from that side.
+	ValidFrom *time.Time `db:"valid_from"`
+	ValidTo   *time.Time `db:"valid_to"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// IsValid reports whether the code can be redeemed at the given moment.
+func (p PromoCode) IsValid(at time.Time) bool {
+	if p.ValidFrom != nil && at.Before(*p.ValidFrom) {
+		return false
+	}
+	if p.ValidTo != nil && !at.Before(*p.ValidTo) {
+		return false
+	}
+	return true
+}
+
+type PromoCodeDisplay struct {
+	Code           string     `json:"code"`
+	Amount         Money      `json:"amount"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	MaxPerUser     int        `json:"max_per_user"`
+	Redemptions    int        `json:"redemptions"`
+	ValidFrom      *time.Time `json:"valid_from,omitempty"`
+	ValidTo        *time.Time `json:"valid_to,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (p PromoCode) ToDisplay() PromoCodeDisplay {
+	return PromoCodeDisplay{
+		Code:           p.Code,
+		Amount:         p.Amount,
+		MaxRedemptions: p.MaxRedemptions,
+		MaxPerUser:     p.MaxPerUser,
+		Redemptions:    p.Redemptions,
+		ValidFrom:      p.ValidFrom,
+		ValidTo:        p.ValidTo,
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+// PromoRedemption is a single use of the promo code by the user.
+type PromoRedemption struct {
+	ID          int       `db:"id"`
+	PromoCodeID int       `db:"promo_code_id"`
+	Code        string    `db:"code"`
+	UserID      int       `db:"user_id"`
+	Amount      Money     `db:"amount"`
+	RedeemedAt  time.Time `db:"redeemed_at"`
+}
+
+type PromoRedemptionDisplay struct {
+	Code       string    `json:"code"`
+	Amount     Money     `json:"amount"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
+
+func (r PromoRedemption) ToDisplay() PromoRedemptionDisplay {
+	return PromoRedemptionDisplay{
+		Code:       r.Code,
+		Amount:     r.Amount,
+		RedeemedAt: r.RedeemedAt,
+	}
+}
+
+// NormalizeCode brings a promo or referral code to the form it's stored in,
+// so the codes are matched case insensitively and ignoring the surrounding spaces.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+type ReferralStatus string
+
+const (
+	// ReferralStatusPending means the invited user has no processed orders yet.
+	ReferralStatusPending ReferralStatus = "PENDING"
+	// ReferralStatusRewarded means both users have got their bonuses.
+	ReferralStatusRewarded ReferralStatus = "REWARDED"
+)
+
+// Referral links the invited user with the user whose code was used at the registration.
+// The bonuses are fixed when the user registers and granted with the first processed order.
+type Referral struct {
+	ID            int            `db:"id"`
+	ReferrerID    int            `db:"referrer_id"`
+	RefereeID     int            `db:"referee_id"`
+	RefereeLogin  string         `db:"referee_login"`
+	Status        ReferralStatus `db:"status"`
+	ReferrerBonus Money          `db:"referrer_bonus"`
+	RefereeBonus  Money          `db:"referee_bonus"`
+	OrderNumber   string         `db:"order_number"`
+	CreatedAt     time.Time      `db:"created_at"`
+	RewardedAt    *time.Time     `db:"rewarded_at"`
+}
+
+// ReferralPolicy tells how the referrals are rewarded.
+type ReferralPolicy struct {
+	ReferrerBonus Money
+	RefereeBonus  Money
+	// MaxReferrals is the limit of the users invited by a single user, 0 means no limit.
+	MaxReferrals int
+}
+
+type ReferralDisplay struct {
+	Login      string         `json:"login"`
+	Status     ReferralStatus `json:"status"`
+	Bonus      Money          `json:"bonus"`
+	CreatedAt  time.Time      `json:"created_at"`
+	RewardedAt *time.Time     `json:"rewarded_at,omitempty"`
+}
+
+// ToDisplay shows the referral to the user who has invited the other one.
+func (r Referral) ToDisplay() ReferralDisplay {
+	return ReferralDisplay{
+		Login:      r.RefereeLogin,
+		Status:     r.Status,
+		Bonus:      r.ReferrerBonus,
+		CreatedAt:  r.CreatedAt,
+		RewardedAt: r.RewardedAt,
+	}
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// AppliedRule explains how a local accrual rule has changed the accrual of the order.
+type AppliedRule struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Accrual is the accrual of the order after the rule is applied
+	Accrual Money `json:"accrual"`
+}
+
+// AppliedRules are stored next to the order as JSON.
+type AppliedRules []AppliedRule
+
+func (r *AppliedRules) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.Errorf("can't scan %T into applied rules", src)
+	}
+
+	return errors.Wrap(json.Unmarshal(data, r), "failed to unmarshal applied rules")
+}
+
+func (r AppliedRules) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal applied rules")
+	}
+	return string(data), nil
+}
+
+// AccrualStats describes the accruals of the user which the rules depend on.
+type AccrualStats struct {
+	// ProcessedOrders is the number of the processed orders of the user
+	ProcessedOrders int `db:"processed_orders"`
+	// AccruedSince is the sum of the accruals of the orders processed since the given moment
+	AccruedSince Money `db:"accrued_since"`
+}
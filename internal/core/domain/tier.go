@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"math/big"
+	"time"
+)
+
+type TierName string
+
+const (
+	TierBronze TierName = "BRONZE"
+	TierSilver TierName = "SILVER"
+	TierGold   TierName = "GOLD"
+)
+
+// Tier is a loyalty level of the user. The tier is earned with the accruals of the processed orders.
+type Tier struct {
+	Name TierName
+	// MinAccrual is the accrual within the window which is needed to reach the tier.
+	MinAccrual Money
+	// Multiplier is applied to the accruals of the orders processed while the user has the tier.
+	Multiplier *big.Rat
+	// Withdrawals restrict how many points the user can spend, 0 means no limit.
+	Withdrawals WithdrawalLimits
+}
+
+// WithdrawalLimits restricts the withdrawals of the user, 0 means no limit.
+type WithdrawalLimits struct {
+	// MaxAmount is the limit of a single withdrawal.
+	MaxAmount Money `json:"max_amount"`
+	// DailyAmount is the limit of all the withdrawals of the user within the last 24 hours.
+	DailyAmount Money `json:"daily_amount"`
+}
+
+// TierPolicy tells how the tiers are calculated.
+type TierPolicy struct {
+	// Window is the period of the accruals which count for the tier, 0 means there are no tiers.
+	Window time.Duration
+	// Tiers come from the configuration sorted by MinAccrual, the first one is given to every new user.
+	Tiers []Tier
+}
+
+func (p TierPolicy) Enabled() bool {
+	return p.Window > 0 && len(p.Tiers) > 0
+}
+
+// AccruedSince returns the beginning of the window at the given moment.
+func (p TierPolicy) AccruedSince(now time.Time) time.Time {
+	return now.Add(-p.Window)
+}
+
+// TierFor returns the highest tier reached with the given accrual.
+func (p TierPolicy) TierFor(accrued Money) Tier {
+	tier := p.Tiers[0]
+	for _, t := range p.Tiers[1:] {
+		if accrued >= t.MinAccrual {
+			tier = t
+		}
+	}
+	return tier
+}
+
+// Tier returns the tier with the given name, unknown names get the first tier.
+func (p TierPolicy) Tier(name TierName) Tier {
+	for _, t := range p.Tiers {
+		if t.Name == name {
+			return t
+		}
+	}
+	return p.Tiers[0]
+}
+
+// TierAccrual is the accrual of the user within the window of the tier policy.
+type TierAccrual struct {
+	UserID  int      `db:"user_id"`
+	Tier    TierName `db:"tier"`
+	Accrued Money    `db:"accrued"`
+}
+
+// TierChange is a record in the tier history of the user.
+type TierChange struct {
+	ID        int       `db:"id"`
+	UserID    int       `db:"user_id"`
+	From      TierName  `db:"from_tier"`
+	To        TierName  `db:"to_tier"`
+	Accrued   Money     `db:"accrued"`
+	ChangedAt time.Time `db:"changed_at"`
+}
+
+type TierChangeDisplay struct {
+	From      TierName  `json:"from"`
+	To        TierName  `json:"to"`
+	Accrued   Money     `json:"accrued"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func (c TierChange) ToDisplay() TierChangeDisplay {
+	return TierChangeDisplay{
+		From:      c.From,
+		To:        c.To,
+		Accrued:   c.Accrued,
+		ChangedAt: c.ChangedAt,
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// Tokens are issued to the user at the login. The access token is short-lived,
+// the refresh token is exchanged for a new pair when the access token expires.
+type Tokens struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// RefreshToken is the server side of the refresh token, only the hash of the token is kept.
+// A token is used once, it's revoked and marked as rotated when it's exchanged for a new one.
+// The tokens issued within one login share the session id.
+type RefreshToken struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	SessionID string     `db:"session_id"`
+	TokenHash string     `db:"token_hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+}
+
+// Session identifies the access token being used, so it can be revoked at the logout.
+type Session struct {
+	UserID    int
+	SessionID string
+	TokenID   string // jti of the access token
+	ExpiresAt time.Time
+}
+
+// PasswordReset lets the user set a new password without the old one. The token is sent
+// to the user and can be used once before it expires.
+type PasswordReset struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
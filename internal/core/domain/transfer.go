@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Transfer moves the points from one user to another.
+type Transfer struct {
+	ID          int       `db:"id"`
+	SenderID    int       `db:"sender_id"`
+	RecipientID int       `db:"recipient_id"`
+	Amount      Money     `db:"amount"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// TransferLimits restricts how many points a user can give away, 0 means no limit.
+type TransferLimits struct {
+	// MaxAmount is the limit of a single transfer.
+	MaxAmount Money
+	// DailyAmount is the limit of all the transfers of the user within the last 24 hours.
+	DailyAmount Money
+}
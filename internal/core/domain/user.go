@@ -7,4 +7,10 @@ type User struct {
 	Login     string    `db:"login"`
 	Password  string    `db:"password"` // hashed Password
 	CreatedAt time.Time `db:"created_at"`
+
+	// ReferralCode is given to the other users to invite them
+	ReferralCode string `db:"referral_code"`
+
+	// TokenVersion is bumped to revoke all the access tokens issued to the user
+	TokenVersion int `db:"token_version"`
 }
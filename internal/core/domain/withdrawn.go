@@ -2,24 +2,44 @@ package domain
 
 import "time"
 
+type WithdrawnStatus string
+
+const (
+	// WithdrawnStatusPending means the points are spent, but the shop hasn't confirmed the order yet.
+	WithdrawnStatusPending WithdrawnStatus = "PENDING"
+	// WithdrawnStatusCompleted means the shop has confirmed the order paid with the points.
+	WithdrawnStatusCompleted WithdrawnStatus = "COMPLETED"
+	// WithdrawnStatusReversed means the points are given back to the user.
+	WithdrawnStatusReversed WithdrawnStatus = "REVERSED"
+)
+
 type Withdrawn struct {
-	ID          int       `db:"id"`
-	OrderNumber string    `db:"order_number"`
-	Sum         int       `db:"sum"`
-	ProcessedAt time.Time `db:"processed_at"`
-	UserID      int       `db:"user_id"`
+	ID             int             `db:"id"`
+	OrderNumber    string          `db:"order_number"`
+	Sum            Money           `db:"sum"`
+	ProcessedAt    time.Time       `db:"processed_at"`
+	UserID         int             `db:"user_id"`
+	Status         WithdrawnStatus `db:"status"`
+	ReversedAt     *time.Time      `db:"reversed_at"`
+	ReversalReason string          `db:"reversal_reason"`
 }
 
 type WithdrawnDisplay struct {
-	OrderNumber string    `json:"order"`
-	Sum         float64   `json:"sum"`
-	ProcessedAt time.Time `json:"processed_at"`
+	OrderNumber    string          `json:"order"`
+	Sum            Money           `json:"sum"`
+	ProcessedAt    time.Time       `json:"processed_at"`
+	Status         WithdrawnStatus `json:"status"`
+	ReversedAt     *time.Time      `json:"reversed_at,omitempty"`
+	ReversalReason string          `json:"reversal_reason,omitempty"`
 }
 
 func (w Withdrawn) ToDisplay() WithdrawnDisplay {
 	return WithdrawnDisplay{
-		OrderNumber: w.OrderNumber,
-		Sum:         float64(w.Sum) / 100,
-		ProcessedAt: w.ProcessedAt,
+		OrderNumber:    w.OrderNumber,
+		Sum:            w.Sum,
+		ProcessedAt:    w.ProcessedAt,
+		Status:         w.Status,
+		ReversedAt:     w.ReversedAt,
+		ReversalReason: w.ReversalReason,
 	}
 }
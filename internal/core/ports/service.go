@@ -3,28 +3,92 @@ package ports
 import (
 	"context"
 	"gophermart/internal/core/domain"
+	"time"
 )
 
 type UserService interface {
-	RegisterUser(ctx context.Context, login, password string) (string, error)
-	LoginUser(ctx context.Context, login, password string) (string, error)
+	RegisterUser(ctx context.Context, login, password, referralCode string) (domain.Tokens, error)
+	LoginUser(ctx context.Context, login, password string) (domain.Tokens, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (domain.Tokens, error)
 	AuthenticateUser(ctx context.Context, token string) (domain.User, error)
+	Logout(ctx context.Context, user *domain.User, token string) error
+	LogoutAll(ctx context.Context, user *domain.User) error
+	ChangePassword(ctx context.Context, user *domain.User, oldPassword, newPassword string) (domain.Tokens, error)
+	RequestPasswordReset(ctx context.Context, login string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
 }
 
 type OrderService interface {
 	AddOrder(ctx context.Context, user *domain.User, orderNumber string) error
 	GetAllOrders(ctx context.Context, user *domain.User) ([]domain.Order, error)
+	GetOrder(ctx context.Context, orderNumber string) (domain.Order, error)
 	GetUserBalance(ctx context.Context, user *domain.User) (domain.UserBalance, error)
-	Withdraw(ctx context.Context, orderNumber string, sum int, user *domain.User) error
+	GetBalanceHistory(ctx context.Context, user *domain.User) ([]domain.LedgerEntry, error)
+	Withdraw(ctx context.Context, orderNumber string, sum domain.Money, user *domain.User) error
+	CompleteWithdrawal(ctx context.Context, orderNumber string) error
+	ReverseWithdrawal(ctx context.Context, orderNumber string, reason string) error
 	GetAllWithdrawals(ctx context.Context, user *domain.User) ([]domain.Withdrawn, error)
 }
 
+type HoldService interface {
+	CreateHold(ctx context.Context, orderNumber string, sum domain.Money, user *domain.User) (domain.Hold, error)
+	CaptureHold(ctx context.Context, holdID int, user *domain.User) (domain.Hold, error)
+	ReleaseHold(ctx context.Context, holdID int, user *domain.User) (domain.Hold, error)
+}
+
+type TransferService interface {
+	Transfer(ctx context.Context, recipientLogin string, sum domain.Money, user *domain.User) (domain.Transfer, error)
+}
+
+type TierService interface {
+	GetTierHistory(ctx context.Context, user *domain.User) ([]domain.TierChange, error)
+}
+
+type ReferralService interface {
+	CheckReferralCode(ctx context.Context, code string) (domain.User, error)
+	AddReferral(ctx context.Context, referrer, referee domain.User) error
+	GetReferrals(ctx context.Context, user *domain.User) ([]domain.Referral, error)
+}
+
+type PromoService interface {
+	CreatePromoCode(ctx context.Context, promo domain.PromoCode) (domain.PromoCode, error)
+	RedeemPromoCode(ctx context.Context, code string, user *domain.User) (domain.PromoRedemption, error)
+}
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, userID int, key string, requestHash string) (domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record domain.IdempotencyRecord) error
+	Abort(ctx context.Context, userID int, key string) error
+}
+
 type AccrualResponse struct {
-	Order   string  `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual"`
+	Order   string               `json:"order"`
+	Status  domain.AccrualStatus `json:"status"`
+	Accrual domain.Money         `json:"accrual"`
 }
 
 type AccrualService interface {
-	CheckAccrual(orderNumber string) (AccrualResponse, error)
+	CheckAccrual(ctx context.Context, orderNumber string) (AccrualResponse, error)
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, notification domain.Notification) error
+}
+
+type AccrualStatsFunc func(ctx context.Context, userID int, since time.Time) (domain.AccrualStats, error)
+
+type ApplyRulesFunc func(
+	ctx context.Context,
+	order domain.Order,
+	at time.Time,
+	stats AccrualStatsFunc,
+) (domain.Order, error)
+
+type AccrualRules interface {
+	ApplyRules(ctx context.Context, order domain.Order, at time.Time, stats AccrualStatsFunc) (domain.Order, error)
+	GrantSignupBonus(ctx context.Context, user domain.User, at time.Time) error
+}
+
+type AccrualProcessor interface {
+	ProcessAccrual(ctx context.Context, resp AccrualResponse) error
 }
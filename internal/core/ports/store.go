@@ -3,22 +3,103 @@ package ports
 import (
 	"context"
 	"gophermart/internal/core/domain"
+	"time"
 )
 
 type UserStore interface {
 	AddNewUser(ctx context.Context, login, passwordHash string) error
 	GetUser(ctx context.Context, login string) (domain.User, error)
+	UpdatePassword(ctx context.Context, userID int, passwordHash string, now time.Time) error
+	CreatePasswordReset(ctx context.Context, reset domain.PasswordReset) error
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) (domain.User, error)
+}
+
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error
+	RotateRefreshToken(
+		ctx context.Context,
+		tokenHash string,
+		next domain.RefreshToken,
+	) (domain.User, domain.RefreshToken, error)
+	RevokeSession(ctx context.Context, session domain.Session, now time.Time) error
+	RevokeAllSessions(ctx context.Context, userID int, now time.Time) error
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
 }
 
 type OrderStore interface {
 	GetOrder(ctx context.Context, orderNumber string) (domain.Order, error)
 	AddNewOrder(ctx context.Context, userID int, orderNumber string) error
 	GetAllOrders(ctx context.Context, userID int) ([]domain.Order, error)
-	GetAllNotFinished(ctx context.Context) ([]domain.Order, error)
-	UpdateOrders(ctx context.Context, orders []domain.Order) error
+	ClaimDueOrders(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]domain.Order, error)
+	UpdateOrders(ctx context.Context, orders []domain.Order, applyRules ApplyRulesFunc) error
 }
 
 type WithdrawnStore interface {
 	GetAllWithdrawals(ctx context.Context, userID int) ([]domain.Withdrawn, error)
-	AddNewWithdrawn(ctx context.Context, orderNumber string, sum int, userID int) error
+	AddNewWithdrawn(
+		ctx context.Context,
+		orderNumber string,
+		sum domain.Money,
+		userID int,
+		limits domain.WithdrawalLimits,
+	) error
+	CompleteWithdrawn(ctx context.Context, orderNumber string) error
+	ReverseWithdrawn(ctx context.Context, orderNumber string, reason string) error
+}
+
+type HoldStore interface {
+	CreateHold(ctx context.Context, hold domain.Hold, limits domain.WithdrawalLimits) (domain.Hold, error)
+	CaptureHold(ctx context.Context, userID int, holdID int, now time.Time) (domain.Hold, error)
+	ReleaseHold(ctx context.Context, userID int, holdID int, now time.Time) (domain.Hold, error)
+	ExpireHolds(ctx context.Context, now time.Time, limit int) (int, error)
+}
+
+type TransferStore interface {
+	AddNewTransfer(
+		ctx context.Context,
+		senderID int,
+		recipientLogin string,
+		sum domain.Money,
+		limits domain.TransferLimits,
+	) (domain.Transfer, error)
+}
+
+type TierStore interface {
+	GetUserTier(ctx context.Context, userID int) (domain.TierName, error)
+	GetTierAccruals(ctx context.Context, since time.Time) ([]domain.TierAccrual, error)
+	ChangeTier(ctx context.Context, change domain.TierChange) (bool, error)
+	GetTierChanges(ctx context.Context, userID int) ([]domain.TierChange, error)
+}
+
+type ReferralStore interface {
+	GetReferrer(ctx context.Context, code string) (domain.User, error)
+	CountReferrals(ctx context.Context, referrerID int) (int, error)
+	AddReferral(ctx context.Context, referral domain.Referral, maxReferrals int) (domain.Referral, error)
+	GetReferrals(ctx context.Context, referrerID int) ([]domain.Referral, error)
+}
+
+type PromoStore interface {
+	CreatePromoCode(ctx context.Context, promo domain.PromoCode) (domain.PromoCode, error)
+	RedeemPromoCode(ctx context.Context, code string, userID int, now time.Time) (domain.PromoRedemption, error)
+}
+
+type LedgerStore interface {
+	GetBalance(ctx context.Context, userID int) (domain.UserBalance, error)
+	GetHistory(ctx context.Context, userID int) ([]domain.LedgerEntry, error)
+	GetLots(ctx context.Context, userID int, earnedBefore time.Time) ([]domain.PointsLot, error)
+	PostTransaction(ctx context.Context, transaction domain.LedgerTransaction) error
+	GetExpiredPointsOwners(ctx context.Context, earnedBefore time.Time) ([]int, error)
+	ExpirePoints(ctx context.Context, userID int, policy domain.ExpiryPolicy, now time.Time) (domain.Money, error)
+}
+
+type IdempotencyStore interface {
+	CreateRecord(
+		ctx context.Context,
+		record domain.IdempotencyRecord,
+		abandonedBefore time.Time,
+	) (domain.IdempotencyRecord, bool, error)
+	CompleteRecord(ctx context.Context, record domain.IdempotencyRecord) error
+	DeleteRecord(ctx context.Context, userID int, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) error
 }
@@ -0,0 +1,137 @@
+package accrualrules
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// AccrualRules adjusts the accruals received from the accrual system with the tier of the user
+// and the local rules, and grants the bonuses which don't depend on the orders.
+type AccrualRules struct {
+	logger      zerolog.Logger
+	ledgerStore ports.LedgerStore
+	tierStore   ports.TierStore
+	rules       Rules
+	tierPolicy  domain.TierPolicy
+}
+
+func New(
+	logService *logging.LoggerService,
+	ledgerStore ports.LedgerStore,
+	tierStore ports.TierStore,
+	rules Rules,
+	tierPolicy domain.TierPolicy,
+) *AccrualRules {
+	return &AccrualRules{
+		logger:      logService.ComponentLogger("AccrualRules"),
+		ledgerStore: ledgerStore,
+		tierStore:   tierStore,
+		rules:       rules,
+		tierPolicy:  tierPolicy,
+	}
+}
+
+// ApplyRules calculates the final accrual of the order which has just been processed.
+// The accrual of the accrual system is kept as the base accrual. The stats of the user
+// are read with the given function, so they are consistent with the order being saved.
+func (a *AccrualRules) ApplyRules(
+	ctx context.Context,
+	order domain.Order,
+	at time.Time,
+	getStats ports.AccrualStatsFunc,
+) (domain.Order, error) {
+	order.BaseAccrual = order.Accrual
+	order.AppliedRules = nil
+
+	if order.Status != domain.OrderStatusProcessed {
+		return order, nil
+	}
+
+	if a.tierPolicy.Enabled() {
+		if err := a.applyTier(ctx, &order); err != nil {
+			return order, err
+		}
+	}
+
+	if len(a.rules) == 0 {
+		return order, nil
+	}
+
+	var stats domain.AccrualStats
+	if a.rules.has(RuleFirstOrderBonus) || a.rules.has(RuleMonthlyCap) {
+		monthStart := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+
+		var err error
+		stats, err = getStats(ctx, order.UserID, monthStart)
+		if err != nil {
+			return order, errors.Wrapf(err, "failed to get accrual stats for order '%s'", order.OrderNumber)
+		}
+	}
+
+	accrual, applied, err := a.rules.apply(order.Accrual, at, stats)
+	if err != nil {
+		return order, errors.Wrapf(err, "failed to apply rules to order '%s'", order.OrderNumber)
+	}
+
+	order.Accrual = accrual
+	order.AppliedRules = append(order.AppliedRules, applied...)
+	return order, nil
+}
+
+// applyTier multiplies the accrual of the order by the multiplier of the current tier of the user.
+func (a *AccrualRules) applyTier(ctx context.Context, order *domain.Order) error {
+	name, err := a.tierStore.GetUserTier(ctx, order.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get tier for order '%s'", order.OrderNumber)
+	}
+
+	tier := a.tierPolicy.Tier(name)
+	accrual, err := order.Accrual.Multiply(tier.Multiplier)
+	if err != nil {
+		return errors.Wrapf(err, "failed to apply tier %s to order '%s'", tier.Name, order.OrderNumber)
+	}
+
+	if accrual != order.Accrual {
+		order.Accrual = accrual
+		order.AppliedRules = append(order.AppliedRules, domain.AppliedRule{
+			Name:    string(tier.Name),
+			Type:    RuleTierMultiplier,
+			Accrual: accrual,
+		})
+	}
+
+	return nil
+}
+
+// GrantSignupBonus credits the signup bonuses which are active at the moment of registration.
+func (a *AccrualRules) GrantSignupBonus(ctx context.Context, user domain.User, at time.Time) error {
+	var bonus domain.Money
+	var names string
+	for _, rule := range a.rules {
+		if rule.Type != RuleSignupBonus || !rule.active(at) || rule.Amount == 0 {
+			continue
+		}
+
+		bonus += rule.Amount
+		if names != "" {
+			names += ","
+		}
+		names += rule.Name
+	}
+
+	if bonus == 0 {
+		return nil
+	}
+
+	if err := a.ledgerStore.PostTransaction(ctx, domain.SignupBonusTransaction(user.ID, bonus, names, at)); err != nil {
+		return errors.Wrapf(err, "failed to grant signup bonus to user %s", user.Login)
+	}
+
+	return nil
+}
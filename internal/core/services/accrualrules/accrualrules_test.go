@@ -0,0 +1,160 @@
+package accrualrules
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testNow        = time.Date(2023, 3, 15, 12, 0, 0, 0, time.UTC)
+	testTierPolicy = domain.TierPolicy{Window: 365 * 24 * time.Hour, Tiers: testTiers}
+)
+
+// testTiers are the tiers of the example configuration.
+var testTiers = []domain.Tier{
+	{
+		Name:        domain.TierBronze,
+		MinAccrual:  0,
+		Multiplier:  big.NewRat(1, 1),
+		Withdrawals: domain.WithdrawalLimits{MaxAmount: 100000, DailyAmount: 300000},
+	},
+	{
+		Name:        domain.TierSilver,
+		MinAccrual:  500000,
+		Multiplier:  big.NewRat(105, 100),
+		Withdrawals: domain.WithdrawalLimits{MaxAmount: 500000, DailyAmount: 1500000},
+	},
+	{
+		Name:       domain.TierGold,
+		MinAccrual: 2000000,
+		Multiplier: big.NewRat(110, 100),
+	},
+}
+
+func TestLoad(t *testing.T) {
+	rules, err := Load("rules.example.json")
+	require.NoError(t, err)
+	assert.Len(t, rules, 4)
+
+	rules, err = Load("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = Load("no-such-file.json")
+	assert.Error(t, err)
+
+	assert.Error(t, Rules{{Name: "x", Type: "unknown"}}.validate())
+	assert.Error(t, Rules{{Name: "x", Type: RuleMultiplier, Multiplier: "abc"}}.validate())
+	assert.Error(t, Rules{{Type: RuleSignupBonus}}.validate())
+}
+
+func TestAccrualRules_ApplyRules(t *testing.T) {
+	rules, err := Load("rules.example.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		accrual domain.Money
+		at      time.Time
+		stats   domain.AccrualStats
+		tier    domain.TierName
+
+		wantAccrual domain.Money
+		wantRules   []string
+	}{
+		{
+			name:        "first order within campaign",
+			accrual:     1050,
+			at:          testNow,
+			tier:        domain.TierBronze,
+			wantAccrual: 12100,
+			wantRules:   []string{"spring-x2", "first-order"},
+		},
+		{
+			name:        "next order out of campaign",
+			accrual:     1050,
+			at:          time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC),
+			stats:       domain.AccrualStats{ProcessedOrders: 1},
+			tier:        domain.TierBronze,
+			wantAccrual: 1050,
+		},
+		{
+			name:        "gold tier out of campaign",
+			accrual:     1050,
+			at:          time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC),
+			stats:       domain.AccrualStats{ProcessedOrders: 1},
+			tier:        domain.TierGold,
+			wantAccrual: 1155,
+			wantRules:   []string{"GOLD"},
+		},
+		{
+			name:        "monthly cap",
+			accrual:     100000,
+			at:          testNow,
+			stats:       domain.AccrualStats{ProcessedOrders: 1, AccruedSince: 450000},
+			tier:        domain.TierSilver,
+			wantAccrual: 50000,
+			wantRules:   []string{"SILVER", "spring-x2", "monthly-cap"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			tierStore := mocks.NewMockTierStore(ctrl)
+			accrualRules := New(logging.New(), mocks.NewMockLedgerStore(ctrl), tierStore, rules, testTierPolicy)
+
+			tierStore.EXPECT().GetUserTier(gomock.Any(), 1).Return(tt.tier, nil)
+
+			monthStart := time.Date(tt.at.Year(), tt.at.Month(), 1, 0, 0, 0, 0, time.UTC)
+			getStats := func(_ context.Context, userID int, since time.Time) (domain.AccrualStats, error) {
+				assert.Equal(t, 1, userID)
+				assert.Equal(t, monthStart, since)
+				return tt.stats, nil
+			}
+
+			order, err := accrualRules.ApplyRules(context.Background(), domain.Order{
+				UserID:  1,
+				Status:  domain.OrderStatusProcessed,
+				Accrual: tt.accrual,
+			}, tt.at, getStats)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.accrual, order.BaseAccrual)
+			assert.Equal(t, tt.wantAccrual, order.Accrual)
+
+			var applied []string
+			for _, rule := range order.AppliedRules {
+				applied = append(applied, rule.Name)
+			}
+			assert.Equal(t, tt.wantRules, applied)
+		})
+	}
+}
+
+func TestAccrualRules_GrantSignupBonus(t *testing.T) {
+	rules, err := Load("rules.example.json")
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	ledgerStore := mocks.NewMockLedgerStore(ctrl)
+	accrualRules := New(logging.New(), ledgerStore, nil, rules, domain.TierPolicy{})
+
+	user := domain.User{ID: 1, Login: "user"}
+	ledgerStore.EXPECT().
+		PostTransaction(gomock.Any(), domain.SignupBonusTransaction(1, 5000, "welcome", testNow)).
+		Return(nil)
+
+	assert.NoError(t, accrualRules.GrantSignupBonus(context.Background(), user, testNow))
+
+	// Nothing is granted without the rule
+	assert.NoError(t, New(logging.New(), ledgerStore, nil, nil, domain.TierPolicy{}).GrantSignupBonus(context.Background(), user, testNow))
+}
@@ -0,0 +1,167 @@
+package accrualrules
+
+import (
+	"encoding/json"
+	"gophermart/internal/core/domain"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RuleMultiplier multiplies the accrual of the orders processed within the campaign dates.
+	RuleMultiplier = "multiplier"
+	// RuleFirstOrderBonus adds the amount to the accrual of the first processed order of the user.
+	RuleFirstOrderBonus = "first_order_bonus"
+	// RuleMonthlyCap limits the accrual of the user within a calendar month.
+	RuleMonthlyCap = "monthly_cap"
+	// RuleSignupBonus grants the amount to every registered user.
+	RuleSignupBonus = "signup_bonus"
+	// RuleTierMultiplier is not configured in the file, it's the multiplier of the tier of the user
+	// which is applied before all the other rules.
+	RuleTierMultiplier = "tier_multiplier"
+)
+
+// stages tells in which order the rules are applied, whatever the order in the file is.
+// Multipliers go first, so the bonuses are not multiplied, and the cap goes last.
+var stages = map[string]int{
+	RuleMultiplier:      0,
+	RuleFirstOrderBonus: 1,
+	RuleMonthlyCap:      2,
+	RuleSignupBonus:     3,
+}
+
+// Rule is a local adjustment of the accruals. Only the fields of its type are used.
+type Rule struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Multiplier is a decimal number, e.g. "1.5"
+	Multiplier string    `json:"multiplier,omitempty"`
+	From       time.Time `json:"from,omitempty"`
+	To         time.Time `json:"to,omitempty"`
+
+	Amount domain.Money `json:"amount,omitempty"`
+
+	factor *big.Rat
+}
+
+// active reports whether the campaign of the rule is on at the given moment.
+// A zero bound means the campaign is not limited from that side.
+func (r Rule) active(at time.Time) bool {
+	if !r.From.IsZero() && at.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !at.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+type Rules []Rule
+
+type rulesFile struct {
+	Rules Rules `json:"rules"`
+}
+
+// Load reads the rules from the JSON file. An empty path means there are no rules.
+func Load(path string) (Rules, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read accrual rules from %s", path)
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse accrual rules from %s", path)
+	}
+
+	return file.Rules, file.Rules.validate()
+}
+
+// validate checks the rules and sorts them in the order they are applied.
+func (r Rules) validate() error {
+	for i := range r {
+		rule := &r[i]
+		if rule.Name == "" {
+			return errors.Errorf("rule #%d has no name", i)
+		}
+
+		switch rule.Type {
+		case RuleMultiplier:
+			factor, ok := new(big.Rat).SetString(rule.Multiplier)
+			if !ok || factor.Sign() < 0 {
+				return errors.Errorf("rule '%s' has invalid multiplier '%s'", rule.Name, rule.Multiplier)
+			}
+			rule.factor = factor
+		case RuleFirstOrderBonus, RuleMonthlyCap, RuleSignupBonus:
+			if rule.Amount < 0 {
+				return errors.Errorf("rule '%s' has negative amount", rule.Name)
+			}
+		default:
+			return errors.Errorf("rule '%s' has unknown type '%s'", rule.Name, rule.Type)
+		}
+	}
+
+	sort.SliceStable(r, func(i, j int) bool {
+		return stages[r[i].Type] < stages[r[j].Type]
+	})
+
+	return nil
+}
+
+func (r Rules) has(ruleType string) bool {
+	for _, rule := range r {
+		if rule.Type == ruleType {
+			return true
+		}
+	}
+	return false
+}
+
+// apply calculates the accrual of the processed order and explains it with the applied rules.
+func (r Rules) apply(accrual domain.Money, at time.Time, stats domain.AccrualStats) (domain.Money, domain.AppliedRules, error) {
+	var applied domain.AppliedRules
+	for _, rule := range r {
+		if !rule.active(at) {
+			continue
+		}
+
+		before := accrual
+		switch rule.Type {
+		case RuleMultiplier:
+			multiplied, err := accrual.Multiply(rule.factor)
+			if err != nil {
+				return accrual, applied, errors.Wrapf(err, "failed to apply rule '%s'", rule.Name)
+			}
+			accrual = multiplied
+		case RuleFirstOrderBonus:
+			if stats.ProcessedOrders == 0 {
+				accrual += rule.Amount
+			}
+		case RuleMonthlyCap:
+			left := rule.Amount - stats.AccruedSince
+			if left < 0 {
+				left = 0
+			}
+			if accrual > left {
+				accrual = left
+			}
+		default:
+			continue
+		}
+
+		if accrual != before {
+			applied = append(applied, domain.AppliedRule{Name: rule.Name, Type: rule.Type, Accrual: accrual})
+		}
+	}
+
+	return accrual, applied, nil
+}
@@ -1,45 +1,161 @@
 package accrualservice
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
 	"gophermart/internal/core/ports"
 	"gophermart/internal/core/services/logging"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
+// defaultRetryAfter is used when the accrual system responds with 429 but does not provide
+// a usable Retry-After header.
+var defaultRetryAfter = 60 * time.Second
+
+// Timeouts used when they are not configured, a request to the accrual system is never unlimited.
+var (
+	defaultConnectTimeout = time.Second
+	defaultReadTimeout    = 3 * time.Second
+)
+
+// Options configures the HTTP client of the accrual system.
+type Options struct {
+	// ConnectTimeout limits the time of establishing a connection.
+	ConnectTimeout time.Duration
+	// ReadTimeout limits the time of waiting for the response after the request is sent.
+	// The whole request including the body is limited by the sum of both timeouts.
+	ReadTimeout time.Duration
+	// MaxIdleConns is the number of kept alive connections to the accrual system.
+	MaxIdleConns int
+	// Retries is the number of extra attempts made after network errors and 5xx answers.
+	Retries int
+	// RetryDelay is the delay before the first retry, it grows linearly with every attempt.
+	RetryDelay time.Duration
+}
+
 type AccrualService struct {
 	accrualSystemAddress string
 	logger               zerolog.Logger
+	client               *http.Client
+	options              Options
 }
 
-func New(accrualSystemAddress string, logService *logging.LoggerService) *AccrualService {
+func New(accrualSystemAddress string, logService *logging.LoggerService, options Options) *AccrualService {
+	if options.ConnectTimeout <= 0 {
+		options.ConnectTimeout = defaultConnectTimeout
+	}
+	if options.ReadTimeout <= 0 {
+		options.ReadTimeout = defaultReadTimeout
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   options.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          options.MaxIdleConns,
+		MaxIdleConnsPerHost:   options.MaxIdleConns,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: options.ReadTimeout,
+	}
+
 	return &AccrualService{
 		accrualSystemAddress: accrualSystemAddress,
 		logger:               logService.ComponentLogger("AccrualService"),
+		client:               &http.Client{Transport: transport, Timeout: options.ConnectTimeout + options.ReadTimeout},
+		options:              options,
 	}
 }
 
-func (a *AccrualService) CheckAccrual(orderNumber string) (ports.AccrualResponse, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/api/orders/%s", a.accrualSystemAddress, orderNumber))
+func (a *AccrualService) CheckAccrual(ctx context.Context, orderNumber string) (ports.AccrualResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resp, retryable, err := a.checkAccrual(ctx, orderNumber)
+		if err == nil || !retryable || attempt >= a.options.Retries {
+			return resp, err
+		}
+
+		delay := a.options.RetryDelay * time.Duration(attempt+1)
+		a.logger.Debug().Err(err).Str("order", orderNumber).Dur("delay", delay).Msg("retry accrual system request")
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ports.AccrualResponse{}, errors.Wrapf(ctx.Err(), "order '%s' check is cancelled", orderNumber)
+		}
+	}
+}
+
+// checkAccrual makes a single request to the accrual system and reports whether it makes sense
+// to repeat the request in case of error.
+func (a *AccrualService) checkAccrual(ctx context.Context, orderNumber string) (ports.AccrualResponse, bool, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/api/orders/%s", a.accrualSystemAddress, orderNumber),
+		nil,
+	)
 	if err != nil {
-		return ports.AccrualResponse{}, errors.Wrapf(err, "failed to get order '%s' from accrual system", orderNumber)
+		return ports.AccrualResponse{}, false, errors.Wrapf(err, "failed to create request for order '%s'", orderNumber)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return ports.AccrualResponse{}, errors.Errorf("accrual service wrong status: %d", resp.StatusCode)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return ports.AccrualResponse{}, ctx.Err() == nil, errors.Wrapf(err, "failed to get order '%s' from accrual system", orderNumber)
 	}
 
 	defer resp.Body.Close()
 
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNoContent:
+		return ports.AccrualResponse{
+			Order:  orderNumber,
+			Status: domain.AccrualStatusNotRegistered,
+		}, false, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ports.AccrualResponse{}, false, &apperrors.RateLimitError{
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	default:
+		return ports.AccrualResponse{}, resp.StatusCode >= http.StatusInternalServerError,
+			errors.Errorf("accrual service wrong status: %d", resp.StatusCode)
+	}
+
 	var accrualResponse ports.AccrualResponse
 	if err := json.NewDecoder(resp.Body).Decode(&accrualResponse); err != nil {
-		return ports.AccrualResponse{}, errors.Wrapf(err, "failed to decode body for order '%s'", orderNumber)
+		return ports.AccrualResponse{}, false, errors.Wrapf(err, "failed to decode body for order '%s'", orderNumber)
+	}
+
+	return accrualResponse, false, nil
+}
+
+// parseRetryAfter supports both forms of the Retry-After header: a number of seconds
+// and an HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return defaultRetryAfter
 	}
 
-	return accrualResponse, nil
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+		return 0
+	}
 
+	return defaultRetryAfter
 }
@@ -0,0 +1,184 @@
+package accrualservice
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrualService_CheckAccrual(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+
+		wantErr        bool
+		wantRetryAfter time.Duration
+		wantStatus     domain.AccrualStatus
+	}{
+		{
+			name:       "success case",
+			status:     http.StatusOK,
+			body:       `{"order": "12345678903", "status": "PROCESSED", "accrual": 500}`,
+			wantStatus: domain.AccrualStatusProcessed,
+		},
+		{
+			name:       "order is not registered",
+			status:     http.StatusNoContent,
+			wantStatus: domain.AccrualStatusNotRegistered,
+		},
+		{
+			name:           "too many requests",
+			status:         http.StatusTooManyRequests,
+			retryAfter:     "30",
+			wantErr:        true,
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:           "too many requests without retry after",
+			status:         http.StatusTooManyRequests,
+			wantErr:        true,
+			wantRetryAfter: defaultRetryAfter,
+		},
+		{
+			name:    "internal server error",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/orders/12345678903", r.URL.Path)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			accrualService := New(srv.URL, logging.New(), Options{})
+			resp, err := accrualService.CheckAccrual(context.Background(), "12345678903")
+
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, resp.Status)
+				return
+			}
+
+			require.Error(t, err)
+			var rateLimitErr *apperrors.RateLimitError
+			if tt.wantRetryAfter != 0 {
+				require.True(t, errors.As(err, &rateLimitErr), "error should be a rate limit error")
+				assert.Equal(t, tt.wantRetryAfter, rateLimitErr.RetryAfter)
+			} else {
+				assert.False(t, errors.As(err, &rateLimitErr), "error should not be a rate limit error")
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 60*time.Second, parseRetryAfter("60", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("", now))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("soon", now))
+}
+
+func TestAccrualService_Retries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"order": "12345678903", "status": "PROCESSING"}`))
+	}))
+	defer srv.Close()
+
+	accrualService := New(srv.URL, logging.New(), Options{Retries: 2, RetryDelay: time.Millisecond})
+	resp, err := accrualService.CheckAccrual(context.Background(), "12345678903")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.AccrualStatusProcessing, resp.Status)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestAccrualService_Timeouts(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	t.Run("read timeout", func(t *testing.T) {
+		accrualService := New(srv.URL, logging.New(), Options{
+			ConnectTimeout: time.Second,
+			ReadTimeout:    50 * time.Millisecond,
+		})
+
+		_, err := accrualService.CheckAccrual(context.Background(), "12345678903")
+		assert.Error(t, err)
+	})
+
+	t.Run("only read timeout is set", func(t *testing.T) {
+		accrualService := New(srv.URL, logging.New(), Options{ReadTimeout: 50 * time.Millisecond})
+		assert.Equal(t, defaultConnectTimeout+50*time.Millisecond, accrualService.client.Timeout)
+
+		accrualService = New(srv.URL, logging.New(), Options{ConnectTimeout: 50 * time.Millisecond})
+		assert.Equal(t, 50*time.Millisecond+defaultReadTimeout, accrualService.client.Timeout)
+	})
+
+	t.Run("body is read too long", func(t *testing.T) {
+		// The headers come in time, but the body never ends
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, `{"order": "12345678903",`)
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer srv.Close()
+
+		accrualService := New(srv.URL, logging.New(), Options{
+			ConnectTimeout: 50 * time.Millisecond,
+			ReadTimeout:    50 * time.Millisecond,
+		})
+
+		_, err := accrualService.CheckAccrual(context.Background(), "12345678903")
+		assert.Error(t, err)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		accrualService := New(srv.URL, logging.New(), Options{Retries: 3, RetryDelay: time.Millisecond})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := accrualService.CheckAccrual(ctx, "12345678903")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
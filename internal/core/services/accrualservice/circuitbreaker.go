@@ -0,0 +1,160 @@
+package accrualservice
+
+import (
+	"context"
+	"expvar"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type BreakerState string
+
+const (
+	BreakerStateClosed   BreakerState = "closed"
+	BreakerStateOpen     BreakerState = "open"
+	BreakerStateHalfOpen BreakerState = "half-open"
+)
+
+// Metrics of the circuit breaker, published at /api/internal/debug/vars.
+var (
+	breakerMetrics  = expvar.NewMap("accrual_circuit_breaker")
+	breakerState    = new(expvar.String)
+	breakerOpened   = new(expvar.Int)
+	breakerRejected = new(expvar.Int)
+)
+
+func init() {
+	breakerState.Set(string(BreakerStateClosed))
+	breakerMetrics.Set("state", breakerState)
+	breakerMetrics.Set("opened_total", breakerOpened)
+	breakerMetrics.Set("rejected_total", breakerRejected)
+}
+
+// CircuitBreaker wraps the accrual service and stops calling it after a number of
+// consecutive failures. While the breaker is open all calls fail fast with ErrCircuitOpen.
+// After the open timeout a single probe call is let through: its success closes the breaker,
+// its failure opens it again.
+type CircuitBreaker struct {
+	accrualService ports.AccrualService
+	logger         zerolog.Logger
+	threshold      int
+	openTimeout    time.Duration
+	now            func() time.Time
+
+	mx       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(
+	accrualService ports.AccrualService,
+	logService *logging.LoggerService,
+	threshold int,
+	openTimeout time.Duration,
+) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &CircuitBreaker{
+		accrualService: accrualService,
+		logger:         logService.ComponentLogger("AccrualCircuitBreaker"),
+		threshold:      threshold,
+		openTimeout:    openTimeout,
+		now:            time.Now,
+		state:          BreakerStateClosed,
+	}
+}
+
+func (b *CircuitBreaker) CheckAccrual(ctx context.Context, orderNumber string) (ports.AccrualResponse, error) {
+	if err := b.allow(); err != nil {
+		return ports.AccrualResponse{}, err
+	}
+
+	resp, err := b.accrualService.CheckAccrual(ctx, orderNumber)
+	b.record(ctx, err)
+
+	return resp, err
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	switch b.state {
+	case BreakerStateClosed:
+		return nil
+	case BreakerStateOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			break
+		}
+		b.setState(BreakerStateHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerStateHalfOpen:
+		if !b.probing {
+			b.probing = true
+			return nil
+		}
+	}
+
+	breakerRejected.Add(1)
+	return errors.Wrapf(apperrors.ErrCircuitOpen, "accrual system calls are suspended since %s", b.openedAt)
+}
+
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.state == BreakerStateHalfOpen {
+		b.probing = false
+	}
+
+	// The call cancelled by the caller tells nothing about the accrual system.
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	// The accrual system which asks to slow down is alive, so it's not a failure.
+	var rateLimitErr *apperrors.RateLimitError
+	if err == nil || errors.As(err, &rateLimitErr) {
+		b.failures = 0
+		if b.state != BreakerStateClosed {
+			b.setState(BreakerStateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerStateHalfOpen || (b.state == BreakerStateClosed && b.failures >= b.threshold) {
+		b.openedAt = b.now()
+		b.setState(BreakerStateOpen)
+		breakerOpened.Add(1)
+	}
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.logger.Warn().
+		Str("from", string(b.state)).
+		Str("to", string(state)).
+		Int("failures", b.failures).
+		Msg("accrual system circuit breaker changed its state")
+
+	b.state = state
+	breakerState.Set(string(state))
+}
@@ -0,0 +1,66 @@
+package accrualservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	accrualService := mocks.NewMockAccrualService(ctrl)
+	breaker := NewCircuitBreaker(accrualService, logging.New(), 2, time.Minute)
+
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+
+	failure := errors.New("connection refused")
+
+	// Consecutive failures open the breaker
+	accrualService.EXPECT().CheckAccrual(gomock.Any(), "1").Return(ports.AccrualResponse{}, failure).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err := breaker.CheckAccrual(context.Background(), "1")
+		assert.ErrorIs(t, err, failure)
+	}
+	assert.Equal(t, BreakerStateOpen, breaker.State())
+
+	// The open breaker fails fast without calling the accrual system
+	_, err := breaker.CheckAccrual(context.Background(), "1")
+	assert.ErrorIs(t, err, apperrors.ErrCircuitOpen)
+
+	// The failed probe opens the breaker again
+	now = now.Add(time.Minute)
+	accrualService.EXPECT().CheckAccrual(gomock.Any(), "1").Return(ports.AccrualResponse{}, failure).Times(1)
+	_, err = breaker.CheckAccrual(context.Background(), "1")
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, BreakerStateOpen, breaker.State())
+
+	_, err = breaker.CheckAccrual(context.Background(), "1")
+	assert.ErrorIs(t, err, apperrors.ErrCircuitOpen)
+
+	// The successful probe closes the breaker
+	now = now.Add(time.Minute)
+	accrualService.EXPECT().CheckAccrual(gomock.Any(), "1").Return(ports.AccrualResponse{Order: "1"}, nil).Times(1)
+	_, err = breaker.CheckAccrual(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, BreakerStateClosed, breaker.State())
+
+	// Rate limit is not a failure
+	accrualService.EXPECT().
+		CheckAccrual(gomock.Any(), "1").
+		Return(ports.AccrualResponse{}, &apperrors.RateLimitError{RetryAfter: time.Second}).
+		Times(3)
+	for i := 0; i < 3; i++ {
+		_, err := breaker.CheckAccrual(context.Background(), "1")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, BreakerStateClosed, breaker.State())
+}
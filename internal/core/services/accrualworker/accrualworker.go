@@ -2,82 +2,358 @@ package accrualworker
 
 import (
 	"context"
+	"gophermart/internal/core/apperrors"
 	"gophermart/internal/core/domain"
 	"gophermart/internal/core/ports"
 	"gophermart/internal/core/services/logging"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
-var checkInterval = 5 * time.Second
+var (
+	checkInterval = 5 * time.Second
+	storeTimeout  = 5 * time.Second
+)
+
+// Options configures how aggressively the worker polls the accrual system.
+type Options struct {
+	// Workers is the number of goroutines sending requests to the accrual system.
+	Workers int
+	// RateLimit is the maximum number of requests per second, 0 means no limit.
+	RateLimit int
+	// BatchSize is the number of orders saved with a single UpdateOrders call.
+	BatchSize int
+
+	// MinBackoff is the delay before the next check of an order which didn't change.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between two checks of the same order.
+	MaxBackoff time.Duration
+	// MaxAge is how long an order may stay unfinished before it's flagged for manual review,
+	// 0 means never.
+	MaxAge time.Duration
+
+	// ClaimLimit is the maximum number of orders taken by the instance in one cycle.
+	ClaimLimit int
+	// Lease is how long claimed orders are hidden from other instances.
+	// It should be longer than a polling cycle.
+	Lease time.Duration
+}
 
 type AccrualWorker struct {
 	accrualService ports.AccrualService
 	orderStore     ports.OrderStore
+	accrualRules   ports.AccrualRules
 	logger         zerolog.Logger
-	stopChan       chan struct{}
+	options        Options
+	now            func() time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// pausedUntil is set when the accrual system asks us to slow down,
+	// no requests are sent until this moment.
+	pausedUntil time.Time
+	pauseMx     sync.RWMutex
 }
 
 func New(
 	accrualService ports.AccrualService,
 	orderStore ports.OrderStore,
+	accrualRules ports.AccrualRules,
 	logService *logging.LoggerService,
+	options Options,
 ) *AccrualWorker {
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+	if options.BatchSize < 1 {
+		options.BatchSize = 1
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = checkInterval
+	}
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = options.MinBackoff
+	}
+	if options.ClaimLimit < 1 {
+		options.ClaimLimit = 1
+	}
+	if options.Lease <= 0 {
+		options.Lease = time.Minute
+	}
+
 	return &AccrualWorker{
 		orderStore:     orderStore,
+		accrualRules:   accrualRules,
 		logger:         logService.ComponentLogger("AccrualWorker"),
-		stopChan:       make(chan struct{}),
 		accrualService: accrualService,
+		options:        options,
+		now:            time.Now,
 	}
 }
 
 func (a *AccrualWorker) Run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+
 	ticker := time.NewTicker(checkInterval)
 	go func() {
+		defer close(a.done)
+		defer ticker.Stop()
+
 		for {
 			select {
 			case <-ticker.C:
-				a.checkOrders()
-			case <-a.stopChan:
+				a.checkOrders(ctx)
+			case <-ctx.Done():
 				return
 			}
 		}
 	}()
 }
 
+// Stop cancels the current polling cycle and waits until all the workers are finished.
 func (a *AccrualWorker) Stop() {
-	close(a.stopChan)
+	a.cancel()
+	<-a.done
 }
 
-func (a *AccrualWorker) checkOrders() {
+func (a *AccrualWorker) checkOrders(ctx context.Context) {
+	if a.isPaused(a.now()) {
+		a.logger.Debug().Time("paused_until", a.getPausedUntil()).Msg("accrual system polling is paused")
+		return
+	}
+
 	a.logger.Info().Msg("process unfinished orders")
-	ctx, cancel := context.WithTimeout(context.Background(), checkInterval)
-	defer cancel()
 
-	orders, err := a.orderStore.GetAllNotFinished(ctx)
+	storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+	orders, err := a.orderStore.ClaimDueOrders(storeCtx, a.now(), a.options.ClaimLimit, a.options.Lease)
+	cancel()
 	if err != nil {
 		a.logger.Error().Err(err).Msg("failed to get list of orders to check")
 		return
 	}
 
-	var updatedOrders []domain.Order
+	// cycleCtx is cancelled as soon as the accrual system asks us to slow down,
+	// so the rest of the orders wait for the next cycle.
+	cycleCtx, stopCycle := context.WithCancel(ctx)
+	defer stopCycle()
+
+	limiter := newRateLimiter(a.options.RateLimit)
+	defer limiter.Stop()
+
+	jobs := make(chan domain.Order)
+	results := make(chan domain.Order)
 
-	for _, order := range orders {
-		resp, err := a.accrualService.CheckAccrual(order.OrderNumber)
-		if err != nil {
-			a.logger.Error().Err(err).Msg("failed to fetch order from accrual system")
-			continue
+	go func() {
+		defer close(jobs)
+		for _, order := range orders {
+			select {
+			case jobs <- order:
+			case <-cycleCtx.Done():
+				return
+			}
 		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < a.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for order := range jobs {
+				if err := limiter.Wait(cycleCtx); err != nil {
+					continue
+				}
+
+				if updated, ok := a.checkOrder(cycleCtx, order, stopCycle); ok {
+					results <- updated
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	a.saveOrders(results)
+}
+
+// checkOrder asks the accrual system about the order and reports whether the order should be saved.
+func (a *AccrualWorker) checkOrder(
+	ctx context.Context,
+	order domain.Order,
+	stopCycle context.CancelFunc,
+) (domain.Order, bool) {
+	resp, err := a.accrualService.CheckAccrual(ctx, order.OrderNumber)
 
-		if resp.Status != string(order.Status) {
-			order.Status = domain.OrderStatus(resp.Status)
-			order.Accrual = int(resp.Accrual * 100)
-			updatedOrders = append(updatedOrders, order)
+	var rateLimitErr *apperrors.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		a.pause(a.now().Add(rateLimitErr.RetryAfter))
+		stopCycle()
+		return order, false
+	}
+
+	// There is no point to go on with the cycle while the accrual system is down.
+	if errors.Is(err, apperrors.ErrCircuitOpen) {
+		a.logger.Debug().Err(err).Msg("accrual system is unavailable, skip the rest of the orders")
+		stopCycle()
+		return order, false
+	}
+
+	// The cycle is stopped, the order waits for the next one.
+	if err != nil && ctx.Err() != nil {
+		return order, false
+	}
+
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to fetch order from accrual system")
+		return order, false
+	}
+
+	updated, changed, err := a.applyAccrual(order, resp)
+	if errors.Is(err, apperrors.ErrUnknownAccrualStatus) {
+		a.logger.Error().
+			Err(err).
+			Str("order", order.OrderNumber).
+			Str("accrual_status", string(resp.Status)).
+			Msg("accrual system returned unknown status, skip the order")
+		return order, false
+	}
+
+	if err != nil {
+		a.logger.Error().Err(err).Str("order", order.OrderNumber).Msg("failed to apply accrual, skip the order")
+		return order, false
+	}
+
+	if !changed {
+		a.postpone(&updated)
+	}
+
+	return updated, true
+}
+
+// ProcessAccrual applies the result pushed by the accrual system. It's the push counterpart of
+// the polling, the order is updated in exactly the same way.
+func (a *AccrualWorker) ProcessAccrual(ctx context.Context, resp ports.AccrualResponse) error {
+	order, err := a.orderStore.GetOrder(ctx, resp.Order)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get order '%s'", resp.Order)
+	}
+
+	// Final statuses are never changed
+	if order.Status.IsFinal() {
+		return nil
+	}
+
+	updated, changed, err := a.applyAccrual(order, resp)
+	if err != nil {
+		return errors.Wrapf(err, "failed to apply accrual to order '%s'", resp.Order)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := a.orderStore.UpdateOrders(ctx, []domain.Order{updated}, a.accrualRules.ApplyRules); err != nil {
+		return errors.Wrapf(err, "failed to update order '%s'", resp.Order)
+	}
+
+	return nil
+}
+
+// applyAccrual updates the order according to the answer of the accrual system
+// and reports whether the order has changed. The local rules are applied to the processed orders
+// when they are saved.
+func (a *AccrualWorker) applyAccrual(order domain.Order, resp ports.AccrualResponse) (domain.Order, bool, error) {
+	status, err := resp.Status.ToOrderStatus()
+	if err != nil {
+		return order, false, err
+	}
+
+	accrual := resp.Accrual
+	if status == order.Status && accrual == order.Accrual {
+		return order, false, nil
+	}
+
+	order.Status = status
+	order.Accrual = accrual
+	order.Attempts = 0
+	order.NextCheckAt = a.now().Add(a.options.MinBackoff)
+
+	return order, true, nil
+}
+
+// postpone pushes the next check of the unchanged order back exponentially.
+// An order which stays unfinished for too long is flagged for manual review and not polled anymore.
+func (a *AccrualWorker) postpone(order *domain.Order) {
+	now := a.now()
+
+	order.Attempts++
+	order.NextCheckAt = now.Add(backoff(order.Attempts, a.options.MinBackoff, a.options.MaxBackoff))
+
+	if a.options.MaxAge > 0 && now.Sub(order.CreatedAt) > a.options.MaxAge {
+		order.NeedsReview = true
+		a.logger.Warn().
+			Str("order", order.OrderNumber).
+			Int("attempts", order.Attempts).
+			Time("created_at", order.CreatedAt).
+			Msg("order is not finished for too long, flag it for manual review")
+	}
+}
+
+// saveOrders reads the results until the channel is closed and saves them in batches.
+// Batches are saved even when the worker is being stopped, so the answers that are already
+// received from the accrual system are not lost.
+func (a *AccrualWorker) saveOrders(results <-chan domain.Order) {
+	batch := make([]domain.Order, 0, a.options.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+
+		if err := a.orderStore.UpdateOrders(ctx, batch, a.accrualRules.ApplyRules); err != nil {
+			a.logger.Error().Err(err).Int("orders", len(batch)).Msg("failed to update orders")
+		}
+		batch = make([]domain.Order, 0, a.options.BatchSize)
+	}
+
+	for order := range results {
+		batch = append(batch, order)
+		if len(batch) >= a.options.BatchSize {
+			flush()
 		}
 	}
+	flush()
+}
+
+func (a *AccrualWorker) isPaused(now time.Time) bool {
+	return now.Before(a.getPausedUntil())
+}
+
+func (a *AccrualWorker) getPausedUntil() time.Time {
+	a.pauseMx.RLock()
+	defer a.pauseMx.RUnlock()
+
+	return a.pausedUntil
+}
+
+func (a *AccrualWorker) pause(until time.Time) {
+	a.pauseMx.Lock()
+	defer a.pauseMx.Unlock()
 
-	if err := a.orderStore.UpdateOrders(ctx, updatedOrders); err != nil {
-		a.logger.Error().Err(err).Msg("failed to update orders")
+	if until.After(a.pausedUntil) {
+		a.pausedUntil = until
+		a.logger.Warn().Time("paused_until", until).Msg("accrual system rate limit exceeded, pause polling")
 	}
 }
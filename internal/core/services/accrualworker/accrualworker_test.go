@@ -0,0 +1,335 @@
+package accrualworker
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestAccrualWorker_RateLimit(t *testing.T) {
+	wt := newWorkerTest(t, Options{Workers: 1, BatchSize: 10})
+
+	wt.OrderStore.EXPECT().
+		ClaimDueOrders(gomock.Any(), testNow, 100, time.Minute).
+		Return([]domain.Order{
+			{OrderNumber: "1", Status: domain.OrderStatusNew},
+			{OrderNumber: "2", Status: domain.OrderStatusNew},
+			{OrderNumber: "3", Status: domain.OrderStatusNew},
+		}, nil).
+		Times(1)
+
+	gomock.InOrder(
+		wt.AccrualService.EXPECT().
+			CheckAccrual(gomock.Any(), "1").
+			Return(ports.AccrualResponse{Order: "1", Status: domain.AccrualStatusProcessed, Accrual: 1000}, nil),
+		wt.AccrualService.EXPECT().
+			CheckAccrual(gomock.Any(), "2").
+			Return(ports.AccrualResponse{}, &apperrors.RateLimitError{RetryAfter: time.Minute}),
+	)
+
+	wt.OrderStore.EXPECT().
+		UpdateOrders(gomock.Any(), []domain.Order{
+			{
+				OrderNumber: "1",
+				Status:      domain.OrderStatusProcessed,
+				Accrual:     1000,
+				NextCheckAt: testNow.Add(time.Second),
+			},
+		}, gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	wt.Worker.checkOrders(context.Background())
+	assert.True(t, wt.Worker.isPaused(testNow), "worker should be paused after 429")
+
+	// The next tick must touch neither the store nor the accrual system.
+	wt.Worker.checkOrders(context.Background())
+}
+
+func TestAccrualWorker_StatusMapping(t *testing.T) {
+	wt := newWorkerTest(t, Options{Workers: 1, BatchSize: 10})
+
+	wt.OrderStore.EXPECT().
+		ClaimDueOrders(gomock.Any(), testNow, 100, time.Minute).
+		Return([]domain.Order{
+			{OrderNumber: "1", Status: domain.OrderStatusNew},
+			{OrderNumber: "2", Status: domain.OrderStatusNew},
+			{OrderNumber: "3", Status: domain.OrderStatusNew},
+			{OrderNumber: "4", Status: domain.OrderStatusNew},
+		}, nil).
+		Times(1)
+
+	wt.AccrualService.EXPECT().
+		CheckAccrual(gomock.Any(), "1").
+		Return(ports.AccrualResponse{Order: "1", Status: domain.AccrualStatusRegistered}, nil)
+	wt.AccrualService.EXPECT().
+		CheckAccrual(gomock.Any(), "2").
+		Return(ports.AccrualResponse{Order: "2", Status: domain.AccrualStatusNotRegistered}, nil)
+	wt.AccrualService.EXPECT().
+		CheckAccrual(gomock.Any(), "3").
+		Return(ports.AccrualResponse{Order: "3", Status: "UNKNOWN"}, nil)
+	wt.AccrualService.EXPECT().
+		CheckAccrual(gomock.Any(), "4").
+		Return(ports.AccrualResponse{Order: "4", Status: domain.AccrualStatusInvalid}, nil)
+
+	// Unknown status is not saved, REGISTERED and not registered orders stay NEW and are postponed.
+	wt.OrderStore.EXPECT().
+		UpdateOrders(gomock.Any(), []domain.Order{
+			{OrderNumber: "1", Status: domain.OrderStatusNew, Attempts: 1, NextCheckAt: testNow.Add(time.Second)},
+			{OrderNumber: "2", Status: domain.OrderStatusNew, Attempts: 1, NextCheckAt: testNow.Add(time.Second)},
+			{OrderNumber: "4", Status: domain.OrderStatusInvalid, NextCheckAt: testNow.Add(time.Second)},
+		}, gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	wt.Worker.checkOrders(context.Background())
+}
+
+func TestAccrualWorker_Backoff(t *testing.T) {
+	wt := newWorkerTest(t, Options{
+		Workers:    1,
+		BatchSize:  10,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+		MaxAge:     24 * time.Hour,
+	})
+
+	wt.OrderStore.EXPECT().
+		ClaimDueOrders(gomock.Any(), testNow, 100, time.Minute).
+		Return([]domain.Order{
+			{OrderNumber: "1", Status: domain.OrderStatusProcessing, Attempts: 3, CreatedAt: testNow.Add(-time.Hour)},
+			{OrderNumber: "2", Status: domain.OrderStatusProcessing, Attempts: 3, CreatedAt: testNow.Add(-48 * time.Hour)},
+		}, nil).
+		Times(1)
+
+	wt.AccrualService.EXPECT().
+		CheckAccrual(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, orderNumber string) (ports.AccrualResponse, error) {
+			return ports.AccrualResponse{Order: orderNumber, Status: domain.AccrualStatusProcessing}, nil
+		}).
+		Times(2)
+
+	wt.OrderStore.EXPECT().
+		UpdateOrders(gomock.Any(), []domain.Order{
+			{
+				OrderNumber: "1",
+				Status:      domain.OrderStatusProcessing,
+				Attempts:    4,
+				NextCheckAt: testNow.Add(8 * time.Second),
+				CreatedAt:   testNow.Add(-time.Hour),
+			},
+			{
+				OrderNumber: "2",
+				Status:      domain.OrderStatusProcessing,
+				Attempts:    4,
+				NextCheckAt: testNow.Add(8 * time.Second),
+				CreatedAt:   testNow.Add(-48 * time.Hour),
+				NeedsReview: true,
+			},
+		}, gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	wt.Worker.checkOrders(context.Background())
+}
+
+func TestAccrualWorker_Batches(t *testing.T) {
+	wt := newWorkerTest(t, Options{Workers: 4, BatchSize: 3})
+
+	var orders []domain.Order
+	for i := 0; i < 10; i++ {
+		orders = append(orders, domain.Order{OrderNumber: strconv.Itoa(i), Status: domain.OrderStatusNew})
+	}
+
+	wt.OrderStore.EXPECT().
+		ClaimDueOrders(gomock.Any(), testNow, 100, time.Minute).
+		Return(orders, nil).
+		Times(1)
+
+	wt.AccrualService.EXPECT().
+		CheckAccrual(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, orderNumber string) (ports.AccrualResponse, error) {
+			return ports.AccrualResponse{Order: orderNumber, Status: domain.AccrualStatusProcessing}, nil
+		}).
+		Times(len(orders))
+
+	var mx sync.Mutex
+	var saved []string
+	var batches []int
+	wt.OrderStore.EXPECT().
+		UpdateOrders(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, batch []domain.Order, _ ports.ApplyRulesFunc) error {
+			mx.Lock()
+			defer mx.Unlock()
+			batches = append(batches, len(batch))
+			for _, order := range batch {
+				assert.Equal(t, domain.OrderStatusProcessing, order.Status)
+				saved = append(saved, order.OrderNumber)
+			}
+			return nil
+		}).
+		AnyTimes()
+
+	wt.Worker.checkOrders(context.Background())
+
+	assert.Len(t, saved, len(orders))
+	assert.Equal(t, []int{3, 3, 3, 1}, batches)
+}
+
+func TestAccrualWorker_Cancel(t *testing.T) {
+	wt := newWorkerTest(t, Options{Workers: 2, BatchSize: 10})
+
+	wt.OrderStore.EXPECT().
+		ClaimDueOrders(gomock.Any(), testNow, 100, time.Minute).
+		Return([]domain.Order{{OrderNumber: "1"}, {OrderNumber: "2"}}, nil).
+		Times(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Nothing is sent to the accrual system when the context is already cancelled.
+	wt.Worker.checkOrders(ctx)
+}
+
+func TestAccrualWorker_ProcessAccrual(t *testing.T) {
+	wt := newWorkerTest(t, Options{})
+
+	wt.OrderStore.EXPECT().
+		GetOrder(gomock.Any(), "1").
+		Return(domain.Order{OrderNumber: "1", Status: domain.OrderStatusProcessing}, nil)
+	wt.OrderStore.EXPECT().
+		UpdateOrders(gomock.Any(), []domain.Order{
+			{
+				OrderNumber: "1",
+				Status:      domain.OrderStatusProcessed,
+				Accrual:     50050,
+				NextCheckAt: testNow.Add(time.Second),
+			},
+		}, gomock.Any()).
+		Return(nil)
+
+	err := wt.Worker.ProcessAccrual(context.Background(), ports.AccrualResponse{
+		Order:   "1",
+		Status:  domain.AccrualStatusProcessed,
+		Accrual: 50050,
+	})
+	assert.NoError(t, err)
+
+	// Final orders are not changed
+	wt.OrderStore.EXPECT().
+		GetOrder(gomock.Any(), "2").
+		Return(domain.Order{OrderNumber: "2", Status: domain.OrderStatusInvalid}, nil)
+
+	err = wt.Worker.ProcessAccrual(context.Background(), ports.AccrualResponse{
+		Order:  "2",
+		Status: domain.AccrualStatusProcessed,
+	})
+	assert.NoError(t, err)
+
+	// Unknown statuses are rejected
+	wt.OrderStore.EXPECT().
+		GetOrder(gomock.Any(), "3").
+		Return(domain.Order{OrderNumber: "3", Status: domain.OrderStatusNew}, nil)
+
+	err = wt.Worker.ProcessAccrual(context.Background(), ports.AccrualResponse{
+		Order:  "3",
+		Status: "UNKNOWN",
+	})
+	assert.ErrorIs(t, err, apperrors.ErrUnknownAccrualStatus)
+}
+
+func TestAccrualWorker_ApplyRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	orderStore := mocks.NewMockOrderStore(ctrl)
+	accrualRules := mocks.NewMockAccrualRules(ctrl)
+	worker := New(mocks.NewMockAccrualService(ctrl), orderStore, accrualRules, logging.New(), Options{
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+	})
+	worker.now = func() time.Time { return testNow }
+
+	order := domain.Order{OrderNumber: "1", Status: domain.OrderStatusProcessing}
+	processed := domain.Order{
+		OrderNumber: "1",
+		Status:      domain.OrderStatusProcessed,
+		Accrual:     1000,
+		NextCheckAt: testNow.Add(time.Second),
+	}
+	adjusted := processed
+	adjusted.BaseAccrual = 1000
+	adjusted.Accrual = 2000
+	adjusted.AppliedRules = domain.AppliedRules{{Name: "x2", Type: "multiplier", Accrual: 2000}}
+
+	orderStore.EXPECT().GetOrder(gomock.Any(), "1").Return(order, nil)
+	accrualRules.EXPECT().ApplyRules(gomock.Any(), processed, testNow, gomock.Any()).Return(adjusted, nil)
+
+	// The rules are applied by the store when the order is saved
+	orderStore.EXPECT().
+		UpdateOrders(gomock.Any(), []domain.Order{processed}, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, orders []domain.Order, applyRules ports.ApplyRulesFunc) error {
+			order, err := applyRules(ctx, orders[0], testNow, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, adjusted, order)
+			return nil
+		})
+
+	err := worker.ProcessAccrual(context.Background(), ports.AccrualResponse{
+		Order:   "1",
+		Status:  domain.AccrualStatusProcessed,
+		Accrual: 1000,
+	})
+	assert.NoError(t, err)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, backoff(0, time.Second, time.Minute))
+	assert.Equal(t, time.Second, backoff(1, time.Second, time.Minute))
+	assert.Equal(t, 2*time.Second, backoff(2, time.Second, time.Minute))
+	assert.Equal(t, 32*time.Second, backoff(6, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, backoff(7, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, backoff(1000, time.Second, time.Minute))
+}
+
+// -- Test helpers --
+
+type workerTest struct {
+	Worker         *AccrualWorker
+	AccrualService *mocks.MockAccrualService
+	OrderStore     *mocks.MockOrderStore
+	AccrualRules   *mocks.MockAccrualRules
+}
+
+func newWorkerTest(t *testing.T, options Options) *workerTest {
+	if options.MinBackoff == 0 {
+		options.MinBackoff = time.Second
+		options.MaxBackoff = time.Minute
+	}
+	options.ClaimLimit = 100
+	options.Lease = time.Minute
+
+	ctrl := gomock.NewController(t)
+	accrualService := mocks.NewMockAccrualService(ctrl)
+	orderStore := mocks.NewMockOrderStore(ctrl)
+	accrualRules := mocks.NewMockAccrualRules(ctrl)
+	worker := New(accrualService, orderStore, accrualRules, logging.New(), options)
+	worker.now = func() time.Time { return testNow }
+
+	return &workerTest{
+		Worker:         worker,
+		AccrualService: accrualService,
+		OrderStore:     orderStore,
+		AccrualRules:   accrualRules,
+	}
+}
@@ -0,0 +1,21 @@
+package accrualworker
+
+import "time"
+
+// backoff returns the delay before the next check of an order which answer
+// hasn't changed for the given number of attempts. The delay doubles with every
+// attempt starting from minDelay and never exceeds maxDelay.
+func backoff(attempts int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := minDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay || delay <= 0 {
+			return maxDelay
+		}
+	}
+
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,44 @@
+package accrualworker
+
+import (
+	"context"
+	"time"
+)
+
+// rateLimiter allows no more than the given number of events per second.
+// A nil limiter doesn't limit anything.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{ticker: time.NewTicker(time.Second / time.Duration(perSecond))}
+}
+
+// Wait blocks until the next event is allowed or the context is done.
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if r == nil {
+		return nil
+	}
+
+	select {
+	case <-r.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *rateLimiter) Stop() {
+	if r != nil {
+		r.ticker.Stop()
+	}
+}
@@ -12,7 +12,15 @@ import (
 	"github.com/pkg/errors"
 )
 
+// defaultMigrations is the location of the migrations relative to the working directory of the service.
+var defaultMigrations = "file://migrations"
+
 func NewDB(dsn string) (*sqlx.DB, error) {
+	return NewDBWithMigrations(dsn, defaultMigrations)
+}
+
+// NewDBWithMigrations connects to the database and applies migrations from the given source.
+func NewDBWithMigrations(dsn string, migrations string) (*sqlx.DB, error) {
 	db, err := sqlx.Connect("pgx", dsn)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to connect to database")
@@ -22,7 +30,7 @@ func NewDB(dsn string) (*sqlx.DB, error) {
 		return nil, errors.Wrap(err, "failed to ping db")
 	}
 
-	if err := bootstrapDatabase(dsn); err != nil {
+	if err := bootstrapDatabase(dsn, migrations); err != nil {
 		return nil, errors.Wrap(err, "failed to bootstrap database")
 	}
 
@@ -38,8 +46,8 @@ func ping(db *sqlx.DB) error {
 	return nil
 }
 
-func bootstrapDatabase(dsn string) error {
-	m, err := migrate.New("file://migrations", dsn)
+func bootstrapDatabase(dsn string, migrations string) error {
+	m, err := migrate.New(migrations, dsn)
 	if err != nil {
 		return errors.Wrap(err, "failed to create a migration instance")
 	}
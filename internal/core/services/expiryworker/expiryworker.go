@@ -0,0 +1,82 @@
+package expiryworker
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var expireTimeout = 5 * time.Minute
+
+// ExpiryWorker periodically burns the points which weren't spent in time.
+type ExpiryWorker struct {
+	ledgerStore ports.LedgerStore
+	logger      zerolog.Logger
+	policy      domain.ExpiryPolicy
+	interval    time.Duration
+	now         func() time.Time
+	stopChan    chan struct{}
+}
+
+func New(
+	ledgerStore ports.LedgerStore,
+	logService *logging.LoggerService,
+	policy domain.ExpiryPolicy,
+	interval time.Duration,
+) *ExpiryWorker {
+	return &ExpiryWorker{
+		ledgerStore: ledgerStore,
+		logger:      logService.ComponentLogger("ExpiryWorker"),
+		policy:      policy,
+		interval:    interval,
+		now:         time.Now,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+func (e *ExpiryWorker) Run() {
+	ticker := time.NewTicker(e.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.expirePoints()
+			case <-e.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (e *ExpiryWorker) Stop() {
+	close(e.stopChan)
+}
+
+func (e *ExpiryWorker) expirePoints() {
+	ctx, cancel := context.WithTimeout(context.Background(), expireTimeout)
+	defer cancel()
+
+	now := e.now()
+	userIDs, err := e.ledgerStore.GetExpiredPointsOwners(ctx, e.policy.EarnedBefore(now))
+	if err != nil {
+		e.logger.Error().Err(err).Msg("failed to get owners of expired points")
+		return
+	}
+
+	for _, userID := range userIDs {
+		expired, err := e.ledgerStore.ExpirePoints(ctx, userID, e.policy, now)
+		if err != nil {
+			e.logger.Error().Err(err).Int("user_id", userID).Msg("failed to expire points")
+			continue
+		}
+
+		if expired > 0 {
+			e.logger.Info().Int("user_id", userID).Stringer("amount", expired).Msg("points are expired")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package expiryworker
+
+import (
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+var testNow = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestExpiryWorker_ExpirePoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ledgerStore := mocks.NewMockLedgerStore(ctrl)
+	policy := domain.ExpiryPolicy{Months: 6}
+
+	worker := New(ledgerStore, logging.New(), policy, time.Hour)
+	worker.now = func() time.Time { return testNow }
+
+	ledgerStore.EXPECT().
+		GetExpiredPointsOwners(gomock.Any(), time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)).
+		Return([]int{1, 2}, nil)
+
+	// A failure for one user doesn't stop the expiration for the others
+	ledgerStore.EXPECT().
+		ExpirePoints(gomock.Any(), 1, policy, testNow).
+		Return(domain.Money(0), errors.New("test error"))
+	ledgerStore.EXPECT().
+		ExpirePoints(gomock.Any(), 2, policy, testNow).
+		Return(domain.Money(500), nil)
+
+	worker.expirePoints()
+}
@@ -0,0 +1,155 @@
+package holdservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var (
+	expireInterval = time.Minute
+	expireLimit    = 100
+)
+
+// HoldService reserves the points while the shop checkout is in progress,
+// the reserved points are captured or released afterwards.
+type HoldService struct {
+	logger     zerolog.Logger
+	holdStore  ports.HoldStore
+	tierStore  ports.TierStore
+	tierPolicy domain.TierPolicy
+	ttl        time.Duration
+	now        func() time.Time
+	stopChan   chan struct{}
+}
+
+func New(
+	logService *logging.LoggerService,
+	holdStore ports.HoldStore,
+	tierStore ports.TierStore,
+	tierPolicy domain.TierPolicy,
+	ttl time.Duration,
+) *HoldService {
+	return &HoldService{
+		logger:     logService.ComponentLogger("HoldService"),
+		holdStore:  holdStore,
+		tierStore:  tierStore,
+		tierPolicy: tierPolicy,
+		ttl:        ttl,
+		now:        time.Now,
+		stopChan:   make(chan struct{}),
+	}
+}
+
+func (h *HoldService) CreateHold(
+	ctx context.Context,
+	orderNumber string,
+	sum domain.Money,
+	user *domain.User,
+) (domain.Hold, error) {
+	if !domain.ValidOrderNumber(orderNumber) {
+		return domain.Hold{}, errors.Wrapf(apperrors.ErrIncorrectOrderFormat, "incorrect order number '%s'", orderNumber)
+	}
+
+	if sum <= 0 {
+		return domain.Hold{}, errors.Wrapf(apperrors.ErrInvalidWithdrawSum, "sum %s is not positive", sum)
+	}
+
+	// The captured hold becomes a withdrawal, so the hold is limited the same way
+	var limits domain.WithdrawalLimits
+	if h.tierPolicy.Enabled() {
+		name, err := h.tierStore.GetUserTier(ctx, user.ID)
+		if err != nil {
+			return domain.Hold{}, errors.Wrapf(err, "failed to get tier of the user %s", user.Login)
+		}
+
+		tier := h.tierPolicy.Tier(name)
+		limits = tier.Withdrawals
+		if limits.MaxAmount > 0 && sum > limits.MaxAmount {
+			return domain.Hold{}, errors.Wrapf(
+				apperrors.ErrWithdrawalLimitExceeded,
+				"sum %s is greater than %s of the tier %s", sum, limits.MaxAmount, tier.Name,
+			)
+		}
+	}
+
+	now := h.now()
+	hold, err := h.holdStore.CreateHold(ctx, domain.Hold{
+		UserID:      user.ID,
+		OrderNumber: orderNumber,
+		Amount:      sum,
+		Status:      domain.HoldStatusActive,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(h.ttl),
+	}, limits)
+	if err != nil {
+		return hold, errors.Wrapf(err, "failed to create a hold for user %s", user.Login)
+	}
+
+	return hold, nil
+}
+
+func (h *HoldService) CaptureHold(ctx context.Context, holdID int, user *domain.User) (domain.Hold, error) {
+	hold, err := h.holdStore.CaptureHold(ctx, user.ID, holdID, h.now())
+	if err != nil {
+		return hold, errors.Wrapf(err, "failed to capture a hold for user %s", user.Login)
+	}
+
+	return hold, nil
+}
+
+func (h *HoldService) ReleaseHold(ctx context.Context, holdID int, user *domain.User) (domain.Hold, error) {
+	hold, err := h.holdStore.ReleaseHold(ctx, user.ID, holdID, h.now())
+	if err != nil {
+		return hold, errors.Wrapf(err, "failed to release a hold for user %s", user.Login)
+	}
+
+	return hold, nil
+}
+
+// Run periodically releases the holds which weren't captured in time.
+func (h *HoldService) Run() {
+	ticker := time.NewTicker(expireInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.expireHolds()
+			case <-h.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (h *HoldService) Stop() {
+	close(h.stopChan)
+}
+
+func (h *HoldService) expireHolds() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for {
+		expired, err := h.holdStore.ExpireHolds(ctx, h.now(), expireLimit)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("failed to expire holds")
+			return
+		}
+
+		if expired > 0 {
+			h.logger.Info().Int("holds", expired).Msg("holds are expired")
+		}
+
+		if expired < expireLimit {
+			return
+		}
+	}
+}
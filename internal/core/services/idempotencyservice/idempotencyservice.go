@@ -0,0 +1,124 @@
+package idempotencyservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var cleanupInterval = time.Hour
+
+type IdempotencyService struct {
+	logger           zerolog.Logger
+	idempotencyStore ports.IdempotencyStore
+	ttl              time.Duration
+	lease            time.Duration
+	now              func() time.Time
+	stopChan         chan struct{}
+}
+
+func New(
+	logService *logging.LoggerService,
+	idempotencyStore ports.IdempotencyStore,
+	ttl time.Duration,
+	lease time.Duration,
+) *IdempotencyService {
+	return &IdempotencyService{
+		logger:           logService.ComponentLogger("IdempotencyService"),
+		idempotencyStore: idempotencyStore,
+		ttl:              ttl,
+		lease:            lease,
+		now:              time.Now,
+		stopChan:         make(chan struct{}),
+	}
+}
+
+// Begin reserves the key for the request. If the request with the key is already processed,
+// its record is returned and the second result is false, the response should be replayed then.
+// A request which is in progress for longer than the lease is considered abandoned,
+// e.g. the instance has crashed, and the key is reserved again.
+func (i *IdempotencyService) Begin(
+	ctx context.Context,
+	userID int,
+	key string,
+	requestHash string,
+) (domain.IdempotencyRecord, bool, error) {
+	now := i.now()
+	record, created, err := i.idempotencyStore.CreateRecord(ctx, domain.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(i.ttl),
+	}, now.Add(-i.lease))
+	if err != nil {
+		return record, false, errors.Wrap(err, "failed to begin idempotent request")
+	}
+
+	if created {
+		return record, true, nil
+	}
+
+	if record.RequestHash != requestHash {
+		return record, false, errors.Wrapf(apperrors.ErrIdempotencyKeyReused, "key '%s'", key)
+	}
+
+	if record.InProgress() {
+		return record, false, errors.Wrapf(apperrors.ErrIdempotencyKeyInProgress, "key '%s'", key)
+	}
+
+	return record, false, nil
+}
+
+// Complete saves the response, so it can be replayed.
+func (i *IdempotencyService) Complete(ctx context.Context, record domain.IdempotencyRecord) error {
+	if err := i.idempotencyStore.CompleteRecord(ctx, record); err != nil {
+		return errors.Wrap(err, "failed to complete idempotent request")
+	}
+
+	return nil
+}
+
+// Abort releases the key, so the request can be retried with the same key.
+func (i *IdempotencyService) Abort(ctx context.Context, userID int, key string) error {
+	if err := i.idempotencyStore.DeleteRecord(ctx, userID, key); err != nil {
+		return errors.Wrap(err, "failed to abort idempotent request")
+	}
+
+	return nil
+}
+
+// Run periodically removes the expired keys.
+func (i *IdempotencyService) Run() {
+	ticker := time.NewTicker(cleanupInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				i.cleanup()
+			case <-i.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (i *IdempotencyService) Stop() {
+	close(i.stopChan)
+}
+
+func (i *IdempotencyService) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := i.idempotencyStore.DeleteExpired(ctx, i.now()); err != nil {
+		i.logger.Error().Err(err).Msg("failed to delete expired idempotency keys")
+	}
+}
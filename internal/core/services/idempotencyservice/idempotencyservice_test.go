@@ -0,0 +1,74 @@
+package idempotencyservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		storedRecord  domain.IdempotencyRecord
+		storedCreated bool
+
+		wantStarted bool
+		wantErrorIs error
+	}{
+		{
+			name:          "new key",
+			storedCreated: true,
+			wantStarted:   true,
+		},
+		{
+			name:         "completed request",
+			storedRecord: domain.IdempotencyRecord{RequestHash: "hash", StatusCode: 200},
+		},
+		{
+			name:         "request in progress",
+			storedRecord: domain.IdempotencyRecord{RequestHash: "hash"},
+			wantErrorIs:  apperrors.ErrIdempotencyKeyInProgress,
+		},
+		{
+			name:         "another request",
+			storedRecord: domain.IdempotencyRecord{RequestHash: "another hash", StatusCode: 200},
+			wantErrorIs:  apperrors.ErrIdempotencyKeyReused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			idempotencyStore := mocks.NewMockIdempotencyStore(ctrl)
+			idempotencyService := New(logging.New(), idempotencyStore, time.Hour, time.Minute)
+			idempotencyService.now = func() time.Time { return now }
+
+			idempotencyStore.EXPECT().
+				CreateRecord(gomock.Any(), domain.IdempotencyRecord{
+					UserID:      1,
+					Key:         "key",
+					RequestHash: "hash",
+					CreatedAt:   now,
+					ExpiresAt:   now.Add(time.Hour),
+				}, now.Add(-time.Minute)).
+				Return(tt.storedRecord, tt.storedCreated, nil).
+				Times(1)
+
+			_, started, err := idempotencyService.Begin(context.Background(), 1, "key", "hash")
+			if tt.wantErrorIs != nil {
+				assert.ErrorIs(t, err, tt.wantErrorIs)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStarted, started)
+		})
+	}
+}
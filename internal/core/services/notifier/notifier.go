@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	SenderStdout = "stdout"
+	SenderFile   = "file"
+)
+
+// Notifier writes the notifications instead of sending them, it's meant for the local development
+// until a real sender such as an email gateway is plugged in.
+type Notifier struct {
+	logger zerolog.Logger
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// New creates the notifier of the sender: stdout or file, the file is appended to.
+// The stdout sender is for the local development only, the notifications end up in the logs.
+func New(logService *logging.LoggerService, sender, path string) (*Notifier, error) {
+	n := &Notifier{logger: logService.ComponentLogger("Notifier")}
+
+	switch sender {
+	case SenderStdout:
+		n.w = os.Stdout
+	case SenderFile:
+		if path == "" {
+			return nil, errors.New("file of the notifications is not set")
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open notifications file %s", path)
+		}
+		n.w = f
+		n.closer = f
+	default:
+		return nil, errors.Errorf("unknown notification sender '%s', should be stdout or file", sender)
+	}
+
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, notification domain.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, err := fmt.Fprintf(
+		n.w, "Login: %s\nSubject: %s\n\n%s\n\n", notification.Login, notification.Subject, notification.Body,
+	); err != nil {
+		return errors.Wrapf(err, "failed to send notification to %s", notification.Login)
+	}
+
+	n.logger.Debug().Str("login", notification.Login).Str("subject", notification.Subject).Msg("notification is sent")
+
+	return nil
+}
+
+func (n *Notifier) Close() error {
+	if n.closer == nil {
+		return nil
+	}
+	return n.closer.Close()
+}
@@ -0,0 +1,36 @@
+package notifier
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.txt")
+
+	n, err := New(logging.New(), SenderFile, path)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = n.Notify(context.Background(), domain.Notification{Login: "user", Subject: "Hello", Body: "Body"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, n.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Login: user\nSubject: Hello\n\nBody\n\nLogin: user\nSubject: Hello\n\nBody\n\n", string(content))
+
+	_, err = New(logging.New(), SenderFile, "")
+	assert.Error(t, err)
+
+	_, err = New(logging.New(), "email", "")
+	assert.Error(t, err)
+}
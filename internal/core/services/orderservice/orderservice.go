@@ -6,6 +6,8 @@ import (
 	"gophermart/internal/core/domain"
 	"gophermart/internal/core/ports"
 	"gophermart/internal/core/services/logging"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -15,22 +17,34 @@ type OrderService struct {
 	logger         zerolog.Logger
 	orderStore     ports.OrderStore
 	withdrawnStore ports.WithdrawnStore
+	ledgerStore    ports.LedgerStore
+	tierStore      ports.TierStore
+	expiryPolicy   domain.ExpiryPolicy
+	tierPolicy     domain.TierPolicy
 }
 
 func New(
 	logService *logging.LoggerService,
 	orderStore ports.OrderStore,
 	withdrawnStore ports.WithdrawnStore,
+	ledgerStore ports.LedgerStore,
+	tierStore ports.TierStore,
+	expiryPolicy domain.ExpiryPolicy,
+	tierPolicy domain.TierPolicy,
 ) *OrderService {
 	return &OrderService{
 		logger:         logService.ComponentLogger("OrderService"),
 		orderStore:     orderStore,
 		withdrawnStore: withdrawnStore,
+		ledgerStore:    ledgerStore,
+		tierStore:      tierStore,
+		expiryPolicy:   expiryPolicy,
+		tierPolicy:     tierPolicy,
 	}
 }
 
 func (o *OrderService) AddOrder(ctx context.Context, user *domain.User, orderNumber string) error {
-	if !luhnValid(orderNumber) {
+	if !domain.ValidOrderNumber(orderNumber) {
 		return errors.Wrapf(apperrors.ErrIncorrectOrderFormat, "incorrect order number '%s'", orderNumber)
 	}
 
@@ -65,58 +79,120 @@ func (o *OrderService) GetAllOrders(ctx context.Context, user *domain.User) ([]d
 	return orders, nil
 }
 
-func (o *OrderService) GetUserBalance(ctx context.Context, user *domain.User) (domain.UserBalance, error) {
-	var balance domain.UserBalance
-
-	orders, err := o.GetAllOrders(ctx, user)
+func (o *OrderService) GetOrder(ctx context.Context, orderNumber string) (domain.Order, error) {
+	order, err := o.orderStore.GetOrder(ctx, orderNumber)
 	if err != nil {
-		return balance, err
+		return order, errors.Wrapf(err, "failed to get the order '%s'", orderNumber)
 	}
 
-	withdrawals, err := o.withdrawnStore.GetAllWithdrawals(ctx, user.ID)
+	return order, nil
+}
+
+func (o *OrderService) GetUserBalance(ctx context.Context, user *domain.User) (domain.UserBalance, error) {
+	balance, err := o.ledgerStore.GetBalance(ctx, user.ID)
 	if err != nil {
-		return balance, errors.Wrapf(err, "failed to get all withdrawals for the user %s", user.Login)
+		return balance, errors.Wrapf(err, "failed to get balance for the user %s", user.Login)
 	}
 
-	var total int
-	for _, order := range orders {
-		if order.Status == domain.OrderStatusProcessed {
-			total += order.Accrual
+	if o.expiryPolicy.Enabled() {
+		now := time.Now()
+		lots, err := o.ledgerStore.GetLots(ctx, user.ID, o.expiryPolicy.EarnedBefore(now.Add(o.expiryPolicy.Notice)))
+		if err != nil {
+			return balance, errors.Wrapf(err, "failed to get expiring points for the user %s", user.Login)
 		}
+		balance.ExpiringSoon = o.expiryPolicy.ExpiringSoon(lots, now)
 	}
 
-	var spent int
-	for _, withdrawn := range withdrawals {
-		spent += withdrawn.Sum
-	}
+	if o.tierPolicy.Enabled() {
+		tier, err := o.getTier(ctx, user)
+		if err != nil {
+			return balance, err
+		}
 
-	balance.Current = total - spent
-	balance.Withdrawn = spent
+		balance.Tier = tier.Name
+		balance.TierMultiplier = tier.Multiplier.FloatString(2)
+		if tier.Withdrawals != (domain.WithdrawalLimits{}) {
+			balance.WithdrawalLimits = &tier.Withdrawals
+		}
+	}
 
 	return balance, nil
 }
 
-func (o *OrderService) Withdraw(ctx context.Context, orderNumber string, sum int, user *domain.User) error {
-	if !luhnValid(orderNumber) {
-		return errors.Wrapf(apperrors.ErrIncorrectOrderFormat, "incorrect order number '%s'", orderNumber)
+func (o *OrderService) getTier(ctx context.Context, user *domain.User) (domain.Tier, error) {
+	name, err := o.tierStore.GetUserTier(ctx, user.ID)
+	if err != nil {
+		return domain.Tier{}, errors.Wrapf(err, "failed to get tier of the user %s", user.Login)
 	}
 
-	balance, err := o.GetUserBalance(ctx, user)
+	return o.tierPolicy.Tier(name), nil
+}
+
+func (o *OrderService) GetBalanceHistory(ctx context.Context, user *domain.User) ([]domain.LedgerEntry, error) {
+	entries, err := o.ledgerStore.GetHistory(ctx, user.ID)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get balance for user %s", user.Login)
+		return entries, errors.Wrapf(err, "failed to get balance history for the user %s", user.Login)
+	}
+
+	return entries, nil
+}
+
+func (o *OrderService) Withdraw(ctx context.Context, orderNumber string, sum domain.Money, user *domain.User) error {
+	if !domain.ValidOrderNumber(orderNumber) {
+		return errors.Wrapf(apperrors.ErrIncorrectOrderFormat, "incorrect order number '%s'", orderNumber)
+	}
+
+	if sum <= 0 {
+		return errors.Wrapf(apperrors.ErrInvalidWithdrawSum, "sum %d is not positive", sum)
 	}
 
-	if balance.Current < sum {
-		return errors.Wrapf(apperrors.ErrNotEnoughMoney, "user %s does not have enough money", user.Login)
+	var limits domain.WithdrawalLimits
+	if o.tierPolicy.Enabled() {
+		tier, err := o.getTier(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		limits = tier.Withdrawals
+		if limits.MaxAmount > 0 && sum > limits.MaxAmount {
+			return errors.Wrapf(
+				apperrors.ErrWithdrawalLimitExceeded,
+				"sum %s is greater than %s of the tier %s", sum, limits.MaxAmount, tier.Name,
+			)
+		}
 	}
 
-	if err = o.withdrawnStore.AddNewWithdrawn(ctx, orderNumber, sum, user.ID); err != nil {
+	// The balance and the daily limit are checked by the store within the same transaction
+	// as the withdrawn is created
+	if err := o.withdrawnStore.AddNewWithdrawn(ctx, orderNumber, sum, user.ID, limits); err != nil {
 		return errors.Wrapf(err, "failed to create a withdrawn for user %s", user.Login)
 	}
 
 	return nil
 }
 
+func (o *OrderService) CompleteWithdrawal(ctx context.Context, orderNumber string) error {
+	if err := o.withdrawnStore.CompleteWithdrawn(ctx, orderNumber); err != nil {
+		return errors.Wrapf(err, "failed to complete withdrawal for order '%s'", orderNumber)
+	}
+
+	return nil
+}
+
+// ReverseWithdrawal gives the points back to the user. The reason is kept for the audit.
+func (o *OrderService) ReverseWithdrawal(ctx context.Context, orderNumber string, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return errors.Wrapf(apperrors.ErrReversalReasonIsEmpty, "order '%s'", orderNumber)
+	}
+
+	if err := o.withdrawnStore.ReverseWithdrawn(ctx, orderNumber, reason); err != nil {
+		return errors.Wrapf(err, "failed to reverse withdrawal for order '%s'", orderNumber)
+	}
+
+	o.logger.Info().Str("order", orderNumber).Str("reason", reason).Msg("withdrawal is reversed")
+	return nil
+}
+
 func (o *OrderService) GetAllWithdrawals(ctx context.Context, user *domain.User) ([]domain.Withdrawn, error) {
 	withdrawals, err := o.withdrawnStore.GetAllWithdrawals(ctx, user.ID)
 	if err != nil {
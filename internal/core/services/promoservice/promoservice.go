@@ -0,0 +1,80 @@
+package promoservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// PromoService manages the promo codes of the marketing campaigns.
+type PromoService struct {
+	logger     zerolog.Logger
+	promoStore ports.PromoStore
+	now        func() time.Time
+}
+
+func New(logService *logging.LoggerService, promoStore ports.PromoStore) *PromoService {
+	return &PromoService{
+		logger:     logService.ComponentLogger("PromoService"),
+		promoStore: promoStore,
+		now:        time.Now,
+	}
+}
+
+func (p *PromoService) CreatePromoCode(ctx context.Context, promo domain.PromoCode) (domain.PromoCode, error) {
+	promo.Code = domain.NormalizeCode(promo.Code)
+	promo.Redemptions = 0
+	promo.CreatedAt = p.now()
+
+	if err := validate(promo); err != nil {
+		return promo, err
+	}
+
+	created, err := p.promoStore.CreatePromoCode(ctx, promo)
+	if err != nil {
+		return created, errors.Wrapf(err, "failed to create promo code '%s'", promo.Code)
+	}
+
+	p.logger.Info().Str("code", created.Code).Stringer("amount", created.Amount).Msg("promo code is created")
+	return created, nil
+}
+
+func validate(promo domain.PromoCode) error {
+	switch {
+	case promo.Code == "":
+		return errors.Wrap(apperrors.ErrInvalidPromoCode, "code is empty")
+	case promo.Amount <= 0:
+		return errors.Wrapf(apperrors.ErrInvalidPromoCode, "amount %s is not positive", promo.Amount)
+	case promo.MaxRedemptions < 0 || promo.MaxPerUser < 0:
+		return errors.Wrap(apperrors.ErrInvalidPromoCode, "limits are negative")
+	case promo.ValidFrom != nil && promo.ValidTo != nil && !promo.ValidFrom.Before(*promo.ValidTo):
+		return errors.Wrap(apperrors.ErrInvalidPromoCode, "window is empty")
+	default:
+		return nil
+	}
+}
+
+func (p *PromoService) RedeemPromoCode(
+	ctx context.Context,
+	code string,
+	user *domain.User,
+) (domain.PromoRedemption, error) {
+	code = domain.NormalizeCode(code)
+	if code == "" {
+		return domain.PromoRedemption{}, errors.Wrap(apperrors.ErrNoSuchPromoCode, "code is empty")
+	}
+
+	// The limits and the window are checked by the store under the lock of the code
+	redemption, err := p.promoStore.RedeemPromoCode(ctx, code, user.ID, p.now())
+	if err != nil {
+		return redemption, errors.Wrapf(err, "failed to redeem promo code for user %s", user.Login)
+	}
+
+	return redemption, nil
+}
@@ -0,0 +1,92 @@
+package promoservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPromoService_CreatePromoCode(t *testing.T) {
+	validTo := testNow.Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		promo   domain.PromoCode
+		want    domain.PromoCode
+		wantErr error
+	}{
+		{
+			name:    "empty code",
+			promo:   domain.PromoCode{Code: "  ", Amount: 100},
+			wantErr: apperrors.ErrInvalidPromoCode,
+		},
+		{
+			name:    "not positive amount",
+			promo:   domain.PromoCode{Code: "spring", Amount: 0},
+			wantErr: apperrors.ErrInvalidPromoCode,
+		},
+		{
+			name:    "negative limit",
+			promo:   domain.PromoCode{Code: "spring", Amount: 100, MaxPerUser: -1},
+			wantErr: apperrors.ErrInvalidPromoCode,
+		},
+		{
+			name:    "empty window",
+			promo:   domain.PromoCode{Code: "spring", Amount: 100, ValidFrom: &validTo, ValidTo: &validTo},
+			wantErr: apperrors.ErrInvalidPromoCode,
+		},
+		{
+			name:  "success case",
+			promo: domain.PromoCode{Code: " spring ", Amount: 100, MaxPerUser: 1, ValidTo: &validTo},
+			want:  domain.PromoCode{Code: "SPRING", Amount: 100, MaxPerUser: 1, ValidTo: &validTo, CreatedAt: testNow},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			promoStore := mocks.NewMockPromoStore(ctrl)
+			service := New(logging.New(), promoStore)
+			service.now = func() time.Time { return testNow }
+
+			if tt.wantErr == nil {
+				promoStore.EXPECT().CreatePromoCode(gomock.Any(), tt.want).Return(tt.want, nil).Times(1)
+			}
+
+			_, err := service.CreatePromoCode(context.Background(), tt.promo)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPromoService_RedeemPromoCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	promoStore := mocks.NewMockPromoStore(ctrl)
+	service := New(logging.New(), promoStore)
+	service.now = func() time.Time { return testNow }
+	user := domain.User{ID: 1, Login: "user"}
+
+	_, err := service.RedeemPromoCode(context.Background(), " ", &user)
+	assert.ErrorIs(t, err, apperrors.ErrNoSuchPromoCode)
+
+	promoStore.EXPECT().
+		RedeemPromoCode(gomock.Any(), "SPRING", 1, testNow).
+		Return(domain.PromoRedemption{ID: 1, Code: "SPRING", UserID: 1, Amount: 100}, nil).
+		Times(1)
+
+	redemption, err := service.RedeemPromoCode(context.Background(), "spring", &user)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Money(100), redemption.Amount)
+}
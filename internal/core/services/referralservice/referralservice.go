@@ -0,0 +1,99 @@
+package referralservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// ReferralService lets the users invite each other with the referral codes.
+type ReferralService struct {
+	logger        zerolog.Logger
+	referralStore ports.ReferralStore
+	policy        domain.ReferralPolicy
+	now           func() time.Time
+}
+
+func New(
+	logService *logging.LoggerService,
+	referralStore ports.ReferralStore,
+	policy domain.ReferralPolicy,
+) *ReferralService {
+	return &ReferralService{
+		logger:        logService.ComponentLogger("ReferralService"),
+		referralStore: referralStore,
+		policy:        policy,
+		now:           time.Now,
+	}
+}
+
+// CheckReferralCode returns the owner of the code if the code can still be used.
+// It's checked before the registration, so the user doesn't get an account with a wrong code.
+func (r *ReferralService) CheckReferralCode(ctx context.Context, code string) (domain.User, error) {
+	code = domain.NormalizeCode(code)
+	if code == "" {
+		return domain.User{}, errors.Wrap(apperrors.ErrNoSuchReferralCode, "code is empty")
+	}
+
+	referrer, err := r.referralStore.GetReferrer(ctx, code)
+	if err != nil {
+		return referrer, errors.Wrapf(err, "failed to check referral code '%s'", code)
+	}
+
+	if r.policy.MaxReferrals > 0 {
+		count, err := r.referralStore.CountReferrals(ctx, referrer.ID)
+		if err != nil {
+			return referrer, errors.Wrapf(err, "failed to check referral code '%s'", code)
+		}
+
+		if count >= r.policy.MaxReferrals {
+			return referrer, errors.Wrapf(
+				apperrors.ErrReferralLimitExceeded,
+				"user %s has already invited %d users", referrer.Login, count,
+			)
+		}
+	}
+
+	return referrer, nil
+}
+
+// AddReferral links the new user with the referrer, the bonuses are granted with the first processed order.
+func (r *ReferralService) AddReferral(ctx context.Context, referrer, referee domain.User) error {
+	if referrer.ID == referee.ID {
+		return errors.Wrapf(apperrors.ErrSelfReferral, "user %s", referee.Login)
+	}
+
+	referral, err := r.referralStore.AddReferral(ctx, domain.Referral{
+		ReferrerID:    referrer.ID,
+		RefereeID:     referee.ID,
+		Status:        domain.ReferralStatusPending,
+		ReferrerBonus: r.policy.ReferrerBonus,
+		RefereeBonus:  r.policy.RefereeBonus,
+		CreatedAt:     r.now(),
+	}, r.policy.MaxReferrals)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add referral of user %s by %s", referee.Login, referrer.Login)
+	}
+
+	r.logger.Info().
+		Int("referral_id", referral.ID).
+		Str("referrer", referrer.Login).
+		Str("referee", referee.Login).
+		Msg("user is invited")
+	return nil
+}
+
+func (r *ReferralService) GetReferrals(ctx context.Context, user *domain.User) ([]domain.Referral, error) {
+	referrals, err := r.referralStore.GetReferrals(ctx, user.ID)
+	if err != nil {
+		return referrals, errors.Wrapf(err, "failed to get referrals of the user %s", user.Login)
+	}
+
+	return referrals, nil
+}
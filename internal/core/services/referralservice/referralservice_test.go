@@ -0,0 +1,88 @@
+package referralservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestReferralService_CheckReferralCode(t *testing.T) {
+	policy := domain.ReferralPolicy{ReferrerBonus: 10000, RefereeBonus: 5000, MaxReferrals: 2}
+	referrer := domain.User{ID: 1, Login: "referrer", ReferralCode: "ABC"}
+
+	tests := []struct {
+		name       string
+		code       string
+		normalized string
+		getErr     error
+		referrals  int
+		wantErr    error
+	}{
+		{name: "empty code", code: " ", wantErr: apperrors.ErrNoSuchReferralCode},
+		{name: "unknown code", code: "XYZ", normalized: "XYZ", getErr: apperrors.ErrNoSuchReferralCode, wantErr: apperrors.ErrNoSuchReferralCode},
+		{name: "limit exceeded", code: "abc", normalized: "ABC", referrals: 2, wantErr: apperrors.ErrReferralLimitExceeded},
+		{name: "success case", code: " abc ", normalized: "ABC", referrals: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			referralStore := mocks.NewMockReferralStore(ctrl)
+			service := New(logging.New(), referralStore, policy)
+
+			if tt.code != " " {
+				referralStore.EXPECT().
+					GetReferrer(gomock.Any(), tt.normalized).
+					Return(referrer, errors.Wrap(tt.getErr, "test")).
+					Times(1)
+			}
+			if tt.getErr == nil && tt.code != " " {
+				referralStore.EXPECT().CountReferrals(gomock.Any(), referrer.ID).Return(tt.referrals, nil).Times(1)
+			}
+
+			user, err := service.CheckReferralCode(context.Background(), tt.code)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, referrer, user)
+		})
+	}
+}
+
+func TestReferralService_AddReferral(t *testing.T) {
+	policy := domain.ReferralPolicy{ReferrerBonus: 10000, RefereeBonus: 5000, MaxReferrals: 2}
+	referrer := domain.User{ID: 1, Login: "referrer"}
+	referee := domain.User{ID: 2, Login: "referee"}
+
+	ctrl := gomock.NewController(t)
+	referralStore := mocks.NewMockReferralStore(ctrl)
+	service := New(logging.New(), referralStore, policy)
+	service.now = func() time.Time { return testNow }
+
+	assert.ErrorIs(t, service.AddReferral(context.Background(), referrer, referrer), apperrors.ErrSelfReferral)
+
+	referralStore.EXPECT().
+		AddReferral(gomock.Any(), domain.Referral{
+			ReferrerID:    1,
+			RefereeID:     2,
+			Status:        domain.ReferralStatusPending,
+			ReferrerBonus: 10000,
+			RefereeBonus:  5000,
+			CreatedAt:     testNow,
+		}, 2).
+		Return(domain.Referral{ID: 1}, nil).
+		Times(1)
+
+	assert.NoError(t, service.AddReferral(context.Background(), referrer, referee))
+}
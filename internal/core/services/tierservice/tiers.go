@@ -0,0 +1,79 @@
+package tierservice
+
+import (
+	"encoding/json"
+	"gophermart/internal/core/domain"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// tierConfig is a tier as it's written in the file.
+type tierConfig struct {
+	Name       domain.TierName `json:"name"`
+	MinAccrual domain.Money    `json:"min_accrual"`
+	// Multiplier is a decimal number, e.g. "1.05"
+	Multiplier  string                  `json:"multiplier"`
+	Withdrawals domain.WithdrawalLimits `json:"withdrawals"`
+}
+
+type tiersFile struct {
+	Tiers []tierConfig `json:"tiers"`
+}
+
+// LoadTiers reads the tiers from the JSON file. An empty path means there are no tiers.
+func LoadTiers(path string) ([]domain.Tier, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read tiers from %s", path)
+	}
+
+	var file tiersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse tiers from %s", path)
+	}
+
+	tiers := make([]domain.Tier, 0, len(file.Tiers))
+	names := make(map[domain.TierName]bool, len(file.Tiers))
+	for i, config := range file.Tiers {
+		if config.Name == "" {
+			return nil, errors.Errorf("tier #%d has no name", i)
+		}
+		if names[config.Name] {
+			return nil, errors.Errorf("tier '%s' is duplicated", config.Name)
+		}
+		names[config.Name] = true
+
+		multiplier, ok := new(big.Rat).SetString(config.Multiplier)
+		if !ok || multiplier.Sign() < 0 {
+			return nil, errors.Errorf("tier '%s' has invalid multiplier '%s'", config.Name, config.Multiplier)
+		}
+
+		if config.MinAccrual < 0 || config.Withdrawals.MaxAmount < 0 || config.Withdrawals.DailyAmount < 0 {
+			return nil, errors.Errorf("tier '%s' has negative amount", config.Name)
+		}
+
+		tiers = append(tiers, domain.Tier{
+			Name:        config.Name,
+			MinAccrual:  config.MinAccrual,
+			Multiplier:  multiplier,
+			Withdrawals: config.Withdrawals,
+		})
+	}
+
+	// The first tier is given to every new user, so it's the one reached without accruals
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].MinAccrual < tiers[j].MinAccrual
+	})
+	if len(tiers) > 0 && tiers[0].MinAccrual != 0 {
+		return nil, errors.Errorf("tier '%s' is the lowest one, it should need no accrual", tiers[0].Name)
+	}
+
+	return tiers, nil
+}
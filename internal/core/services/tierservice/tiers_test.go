@@ -0,0 +1,38 @@
+package tierservice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTiers(t *testing.T) {
+	tiers, err := LoadTiers("tiers.example.json")
+	require.NoError(t, err)
+	assert.Equal(t, testTiers, tiers)
+
+	tiers, err = LoadTiers("")
+	assert.NoError(t, err)
+	assert.Empty(t, tiers)
+
+	_, err = LoadTiers("no-such-file.json")
+	assert.Error(t, err)
+
+	for name, content := range map[string]string{
+		"invalid multiplier": `{"tiers": [{"name": "BRONZE", "multiplier": "abc"}]}`,
+		"duplicated name":    `{"tiers": [{"name": "BRONZE", "multiplier": "1"}, {"name": "BRONZE", "multiplier": "1"}]}`,
+		"no lowest tier":     `{"tiers": [{"name": "SILVER", "min_accrual": 5000, "multiplier": "1"}]}`,
+		"negative limit":     `{"tiers": [{"name": "BRONZE", "multiplier": "1", "withdrawals": {"max_amount": -1}}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tiers.json")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			_, err := LoadTiers(path)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package tierservice
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var recalculateTimeout = 5 * time.Minute
+
+// TierService keeps the tiers of the users up to date with their accruals.
+type TierService struct {
+	logger    zerolog.Logger
+	tierStore ports.TierStore
+	policy    domain.TierPolicy
+	interval  time.Duration
+	now       func() time.Time
+	stopChan  chan struct{}
+}
+
+func New(
+	logService *logging.LoggerService,
+	tierStore ports.TierStore,
+	policy domain.TierPolicy,
+	interval time.Duration,
+) *TierService {
+	return &TierService{
+		logger:    logService.ComponentLogger("TierService"),
+		tierStore: tierStore,
+		policy:    policy,
+		interval:  interval,
+		now:       time.Now,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+func (t *TierService) GetTierHistory(ctx context.Context, user *domain.User) ([]domain.TierChange, error) {
+	changes, err := t.tierStore.GetTierChanges(ctx, user.ID)
+	if err != nil {
+		return changes, errors.Wrapf(err, "failed to get tier history for the user %s", user.Login)
+	}
+
+	return changes, nil
+}
+
+// Run periodically recalculates the tiers, the accruals leaving the window may move the users down.
+func (t *TierService) Run() {
+	ticker := time.NewTicker(t.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.recalculateTiers()
+			case <-t.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (t *TierService) Stop() {
+	close(t.stopChan)
+}
+
+func (t *TierService) recalculateTiers() {
+	ctx, cancel := context.WithTimeout(context.Background(), recalculateTimeout)
+	defer cancel()
+
+	now := t.now()
+	accruals, err := t.tierStore.GetTierAccruals(ctx, t.policy.AccruedSince(now))
+	if err != nil {
+		t.logger.Error().Err(err).Msg("failed to get tier accruals")
+		return
+	}
+
+	for _, accrual := range accruals {
+		tier := t.policy.TierFor(accrual.Accrued)
+		if tier.Name == accrual.Tier {
+			continue
+		}
+
+		changed, err := t.tierStore.ChangeTier(ctx, domain.TierChange{
+			UserID:    accrual.UserID,
+			From:      accrual.Tier,
+			To:        tier.Name,
+			Accrued:   accrual.Accrued,
+			ChangedAt: now,
+		})
+		if err != nil {
+			t.logger.Error().Err(err).Int("user_id", accrual.UserID).Msg("failed to change tier")
+			continue
+		}
+
+		if changed {
+			t.logger.Info().
+				Int("user_id", accrual.UserID).
+				Str("from", string(accrual.Tier)).
+				Str("to", string(tier.Name)).
+				Msg("tier is changed")
+		}
+	}
+}
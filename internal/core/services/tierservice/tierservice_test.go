@@ -0,0 +1,67 @@
+package tierservice
+
+import (
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+var testNow = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// testTiers are the tiers of tiers.example.json.
+var testTiers = []domain.Tier{
+	{
+		Name:        domain.TierBronze,
+		MinAccrual:  0,
+		Multiplier:  big.NewRat(1, 1),
+		Withdrawals: domain.WithdrawalLimits{MaxAmount: 100000, DailyAmount: 300000},
+	},
+	{
+		Name:        domain.TierSilver,
+		MinAccrual:  500000,
+		Multiplier:  big.NewRat(105, 100),
+		Withdrawals: domain.WithdrawalLimits{MaxAmount: 500000, DailyAmount: 1500000},
+	},
+	{
+		Name:       domain.TierGold,
+		MinAccrual: 2000000,
+		Multiplier: big.NewRat(110, 100),
+	},
+}
+
+func TestTierService_RecalculateTiers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tierStore := mocks.NewMockTierStore(ctrl)
+	policy := domain.TierPolicy{Window: 24 * time.Hour, Tiers: testTiers}
+
+	service := New(logging.New(), tierStore, policy, time.Hour)
+	service.now = func() time.Time { return testNow }
+
+	tierStore.EXPECT().
+		GetTierAccruals(gomock.Any(), testNow.Add(-24*time.Hour)).
+		Return([]domain.TierAccrual{
+			{UserID: 1, Tier: domain.TierBronze, Accrued: 100},
+			{UserID: 2, Tier: domain.TierBronze, Accrued: 600000},
+			{UserID: 3, Tier: domain.TierGold, Accrued: 0},
+			{UserID: 4, Tier: domain.TierSilver, Accrued: 3000000},
+		}, nil)
+
+	// The first user keeps the tier, a failure for one user doesn't stop the others
+	tierStore.EXPECT().ChangeTier(gomock.Any(), domain.TierChange{
+		UserID: 2, From: domain.TierBronze, To: domain.TierSilver, Accrued: 600000, ChangedAt: testNow,
+	}).Return(false, errors.New("test error"))
+	tierStore.EXPECT().ChangeTier(gomock.Any(), domain.TierChange{
+		UserID: 3, From: domain.TierGold, To: domain.TierBronze, Accrued: 0, ChangedAt: testNow,
+	}).Return(true, nil)
+	tierStore.EXPECT().ChangeTier(gomock.Any(), domain.TierChange{
+		UserID: 4, From: domain.TierSilver, To: domain.TierGold, Accrued: 3000000, ChangedAt: testNow,
+	}).Return(true, nil)
+
+	service.recalculateTiers()
+}
@@ -0,0 +1,61 @@
+package transferservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/services/logging"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// TransferService lets the users give their points to each other.
+type TransferService struct {
+	logger        zerolog.Logger
+	transferStore ports.TransferStore
+	limits        domain.TransferLimits
+}
+
+func New(
+	logService *logging.LoggerService,
+	transferStore ports.TransferStore,
+	limits domain.TransferLimits,
+) *TransferService {
+	return &TransferService{
+		logger:        logService.ComponentLogger("TransferService"),
+		transferStore: transferStore,
+		limits:        limits,
+	}
+}
+
+func (t *TransferService) Transfer(
+	ctx context.Context,
+	recipientLogin string,
+	sum domain.Money,
+	user *domain.User,
+) (domain.Transfer, error) {
+	if recipientLogin == user.Login {
+		return domain.Transfer{}, errors.Wrapf(apperrors.ErrSelfTransfer, "user %s", user.Login)
+	}
+
+	if sum <= 0 {
+		return domain.Transfer{}, errors.Wrapf(apperrors.ErrInvalidWithdrawSum, "sum %s is not positive", sum)
+	}
+
+	if t.limits.MaxAmount > 0 && sum > t.limits.MaxAmount {
+		return domain.Transfer{}, errors.Wrapf(
+			apperrors.ErrTransferLimitExceeded,
+			"sum %s is greater than %s", sum, t.limits.MaxAmount,
+		)
+	}
+
+	// The balance and the daily limit are checked by the store under the lock
+	transfer, err := t.transferStore.AddNewTransfer(ctx, user.ID, recipientLogin, sum, t.limits)
+	if err != nil {
+		return transfer, errors.Wrapf(err, "failed to transfer points from user %s to %s", user.Login, recipientLogin)
+	}
+
+	return transfer, nil
+}
@@ -0,0 +1,52 @@
+package transferservice
+
+import (
+	"context"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/services/logging"
+	mocks "gophermart/mocks/core/ports"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferService_Transfer(t *testing.T) {
+	limits := domain.TransferLimits{MaxAmount: 10000, DailyAmount: 50000}
+	user := domain.User{ID: 1, Login: "sender"}
+
+	tests := []struct {
+		name       string
+		recipient  string
+		sum        domain.Money
+		storeCalls bool
+		wantErr    error
+	}{
+		{name: "self transfer", recipient: "sender", sum: 100, wantErr: apperrors.ErrSelfTransfer},
+		{name: "not positive sum", recipient: "recipient", sum: 0, wantErr: apperrors.ErrInvalidWithdrawSum},
+		{name: "too big sum", recipient: "recipient", sum: 10001, wantErr: apperrors.ErrTransferLimitExceeded},
+		{name: "success case", recipient: "recipient", sum: 10000, storeCalls: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			transferStore := mocks.NewMockTransferStore(ctrl)
+			service := New(logging.New(), transferStore, limits)
+
+			if tt.storeCalls {
+				transferStore.EXPECT().
+					AddNewTransfer(gomock.Any(), user.ID, tt.recipient, tt.sum, limits).
+					Return(domain.Transfer{ID: 1}, nil).
+					Times(1)
+			}
+
+			_, err := service.Transfer(context.Background(), tt.recipient, tt.sum, &user)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
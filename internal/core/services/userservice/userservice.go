@@ -2,6 +2,11 @@ package userservice
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"gophermart/internal/core/apperrors"
 	"gophermart/internal/core/domain"
 	"gophermart/internal/core/ports"
@@ -14,115 +19,477 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Options tells how long the tokens issued to the users live.
+type Options struct {
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	PasswordResetTTL time.Duration
+}
+
+var cleanupInterval = time.Hour
+
 type UserService struct {
-	logger    zerolog.Logger
-	userStore ports.UserStore
-	secret    string
+	logger          zerolog.Logger
+	userStore       ports.UserStore
+	tokenStore      ports.TokenStore
+	accrualRules    ports.AccrualRules
+	referralService ports.ReferralService
+	notifier        ports.Notifier
+	secret          string
+	options         Options
+	now             func() time.Time
+	stopChan        chan struct{}
 }
 
-func New(secret string, logService *logging.LoggerService, userStore ports.UserStore) *UserService {
+func New(
+	secret string,
+	logService *logging.LoggerService,
+	userStore ports.UserStore,
+	tokenStore ports.TokenStore,
+	accrualRules ports.AccrualRules,
+	referralService ports.ReferralService,
+	notifier ports.Notifier,
+	options Options,
+) *UserService {
 	return &UserService{
-		logger:    logService.ComponentLogger("UserService"),
-		userStore: userStore,
-		secret:    secret,
+		logger:          logService.ComponentLogger("UserService"),
+		userStore:       userStore,
+		tokenStore:      tokenStore,
+		accrualRules:    accrualRules,
+		referralService: referralService,
+		notifier:        notifier,
+		secret:          secret,
+		options:         options,
+		now:             time.Now,
+		stopChan:        make(chan struct{}),
 	}
 }
 
-func (u *UserService) RegisterUser(ctx context.Context, login, password string) (string, error) {
+// accessClaims are the claims of the access token. The token is accepted as long as
+// its version matches the token version of the user.
+type accessClaims struct {
+	Login     string `json:"login"`
+	Version   int    `json:"ver"`
+	SessionID string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// RegisterUser creates a new user, the referral code of the user who has invited them is optional.
+func (u *UserService) RegisterUser(ctx context.Context, login, password, referralCode string) (domain.Tokens, error) {
 	if login == "" {
-		return "", apperrors.ErrLoginIsEmpty
+		return domain.Tokens{}, apperrors.ErrLoginIsEmpty
 	}
 
 	if password == "" {
-		return "", apperrors.ErrPasswordIsEmpty
+		return domain.Tokens{}, apperrors.ErrPasswordIsEmpty
+	}
+
+	var referrer *domain.User
+	if referralCode != "" {
+		user, err := u.referralService.CheckReferralCode(ctx, referralCode)
+		if err != nil {
+			return domain.Tokens{}, errors.Wrap(err, "failed to register user")
+		}
+		referrer = &user
 	}
 
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to generate password hash")
+		return domain.Tokens{}, errors.Wrap(err, "failed to generate password hash")
 	}
 
 	if err := u.userStore.AddNewUser(ctx, login, string(passwordHash)); err != nil {
 		u.logger.Error().Err(err).Msg("failed to add a new user")
-		return "", errors.Wrapf(apperrors.ErrLoginIsBusy, "login '%s' is busy", login)
+		return domain.Tokens{}, errors.Wrapf(apperrors.ErrLoginIsBusy, "login '%s' is busy", login)
+	}
+
+	user, err := u.userStore.GetUser(ctx, login)
+	if err != nil {
+		return domain.Tokens{}, errors.Wrapf(err, "failed to get the new user %s", login)
 	}
 
-	return u.generateJWT(login)
+	u.welcomeUser(ctx, user, referrer)
+
+	return u.issueTokens(ctx, user)
 }
 
-func (u *UserService) LoginUser(ctx context.Context, login, password string) (string, error) {
+// welcomeUser credits the signup bonus of the new user and links them with the referrer.
+// The user is already registered, so the registration doesn't fail if it's not possible,
+// the errors are logged instead.
+func (u *UserService) welcomeUser(ctx context.Context, user domain.User, referrer *domain.User) {
+	if err := u.accrualRules.GrantSignupBonus(ctx, user, u.now()); err != nil {
+		u.logger.Error().Err(err).Str("login", user.Login).Msg("failed to grant signup bonus")
+	}
+
+	if referrer == nil {
+		return
+	}
+
+	if err := u.referralService.AddReferral(ctx, *referrer, user); err != nil {
+		u.logger.Error().Err(err).Str("login", user.Login).Msg("failed to add referral")
+	}
+}
+
+func (u *UserService) LoginUser(ctx context.Context, login, password string) (domain.Tokens, error) {
 	if login == "" {
-		return "", apperrors.ErrLoginIsEmpty
+		return domain.Tokens{}, apperrors.ErrLoginIsEmpty
 	}
 
 	if password == "" {
-		return "", apperrors.ErrPasswordIsEmpty
+		return domain.Tokens{}, apperrors.ErrPasswordIsEmpty
 	}
 
 	user, err := u.userStore.GetUser(ctx, login)
 	if err != nil {
-		return "", errors.Wrap(
+		return domain.Tokens{}, errors.Wrap(
 			apperrors.ErrLoginOrPasswordIncorrect,
 			"user with such login/password does not exist",
 		)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.Wrap(
+		return domain.Tokens{}, errors.Wrap(
 			apperrors.ErrLoginOrPasswordIncorrect,
 			"user with such login/password does not exist",
 		)
 	}
 
-	return u.generateJWT(login)
+	return u.issueTokens(ctx, user)
 }
 
+// RefreshTokens exchanges the refresh token for a new pair of tokens. The refresh token
+// can be used only once, a reused token revokes all the sessions of the user.
+func (u *UserService) RefreshTokens(ctx context.Context, refreshToken string) (domain.Tokens, error) {
+	if refreshToken == "" {
+		return domain.Tokens{}, errors.Wrap(apperrors.ErrInvalidRefreshToken, "refresh token is empty")
+	}
+
+	next, nextToken, err := u.newRefreshToken()
+	if err != nil {
+		return domain.Tokens{}, err
+	}
+
+	user, next, err := u.tokenStore.RotateRefreshToken(ctx, hashToken(refreshToken), next)
+	if errors.Is(err, apperrors.ErrRefreshTokenReused) {
+		u.logger.Warn().Err(err).Msg("refresh token is reused, all the sessions of the user are revoked")
+	}
+	if err != nil {
+		return domain.Tokens{}, errors.Wrap(err, "failed to rotate refresh token")
+	}
+
+	accessToken, expiresAt, err := u.generateJWT(user, next.SessionID)
+	if err != nil {
+		return domain.Tokens{}, err
+	}
+
+	return domain.Tokens{AccessToken: accessToken, RefreshToken: nextToken, ExpiresAt: expiresAt}, nil
+}
+
+// AuthenticateUser checks the access token including whether it has been revoked,
+// so a logged out token is rejected right away and not only when it expires.
 func (u *UserService) AuthenticateUser(ctx context.Context, token string) (domain.User, error) {
-	login, err := u.extractLoginFromToken(token)
+	claims, err := u.parseToken(token)
 	if err != nil {
 		return domain.User{}, errors.Wrap(err, "failed to authenticate user")
 	}
 
-	user, err := u.userStore.GetUser(ctx, login)
+	user, err := u.userStore.GetUser(ctx, claims.Login)
 	if err != nil {
 		return domain.User{}, errors.Wrap(err, "no such user")
 	}
 
+	if claims.Version != user.TokenVersion {
+		return domain.User{}, errors.Wrapf(
+			apperrors.ErrTokenRevoked,
+			"all the sessions of user %s are logged out", user.Login,
+		)
+	}
+
+	revoked, err := u.tokenStore.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return domain.User{}, errors.Wrap(err, "failed to authenticate user")
+	}
+	if revoked {
+		return domain.User{}, errors.Wrapf(apperrors.ErrTokenRevoked, "session of user %s is logged out", user.Login)
+	}
+
 	return user, nil
 }
 
-func (u *UserService) extractLoginFromToken(token string) (string, error) {
-	t, err := jwt.Parse(token, func(token *jwt.Token) (any, error) {
+// Logout revokes the access token and the refresh tokens of the session the token belongs to.
+func (u *UserService) Logout(ctx context.Context, user *domain.User, token string) error {
+	claims, err := u.parseToken(token)
+	if err != nil {
+		return errors.Wrap(err, "failed to logout")
+	}
+
+	session := domain.Session{
+		UserID:    user.ID,
+		SessionID: claims.SessionID,
+		TokenID:   claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if err := u.tokenStore.RevokeSession(ctx, session, u.now()); err != nil {
+		return errors.Wrapf(err, "failed to logout user %s", user.Login)
+	}
+
+	return nil
+}
+
+// LogoutAll revokes all the tokens issued to the user.
+func (u *UserService) LogoutAll(ctx context.Context, user *domain.User) error {
+	if err := u.tokenStore.RevokeAllSessions(ctx, user.ID, u.now()); err != nil {
+		return errors.Wrapf(err, "failed to logout all sessions of user %s", user.Login)
+	}
+
+	return nil
+}
+
+// ChangePassword sets the new password of the user. All the other sessions of the user
+// are logged out, the current one continues with the returned tokens.
+func (u *UserService) ChangePassword(
+	ctx context.Context,
+	user *domain.User,
+	oldPassword, newPassword string,
+) (domain.Tokens, error) {
+	if newPassword == "" {
+		return domain.Tokens{}, apperrors.ErrPasswordIsEmpty
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
+		return domain.Tokens{}, errors.Wrapf(apperrors.ErrPasswordIncorrect, "old password of user %s", user.Login)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.MinCost)
+	if err != nil {
+		return domain.Tokens{}, errors.Wrap(err, "failed to generate password hash")
+	}
+
+	if err := u.userStore.UpdatePassword(ctx, user.ID, string(passwordHash), u.now()); err != nil {
+		return domain.Tokens{}, errors.Wrapf(err, "failed to change password of user %s", user.Login)
+	}
+
+	// The token version is bumped with the password
+	updated, err := u.userStore.GetUser(ctx, user.Login)
+	if err != nil {
+		return domain.Tokens{}, errors.Wrapf(err, "failed to get user %s", user.Login)
+	}
+
+	return u.issueTokens(ctx, updated)
+}
+
+// RequestPasswordReset sends a single-use token to the user to set a new password with.
+// An unknown login isn't reported, so the endpoint can't be used to find the registered users.
+func (u *UserService) RequestPasswordReset(ctx context.Context, login string) error {
+	if u.notifier == nil {
+		return errors.Wrap(apperrors.ErrPasswordResetDisabled, "notifier is not configured")
+	}
+
+	if login == "" {
+		return apperrors.ErrLoginIsEmpty
+	}
+
+	user, err := u.userStore.GetUser(ctx, login)
+	if err != nil {
+		u.logger.Info().Err(err).Str("login", login).Msg("password reset of unknown user is requested")
+		return nil
+	}
+
+	token, err := randomString(32)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate reset token")
+	}
+
+	now := u.now()
+	reset := domain.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.options.PasswordResetTTL),
+	}
+	if err := u.userStore.CreatePasswordReset(ctx, reset); err != nil {
+		return errors.Wrapf(err, "failed to save password reset of user %s", login)
+	}
+
+	if err := u.notifier.Notify(ctx, domain.Notification{
+		Login:   user.Login,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Use the token to set a new password: %s\nThe token can be used once until %s.",
+			token, reset.ExpiresAt.Format(time.RFC3339),
+		),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to send reset token to user %s", login)
+	}
+
+	return nil
+}
+
+// ResetPassword sets the new password with the reset token, all the sessions of the user are logged out.
+func (u *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if u.notifier == nil {
+		return errors.Wrap(apperrors.ErrPasswordResetDisabled, "notifier is not configured")
+	}
+
+	if token == "" {
+		return errors.Wrap(apperrors.ErrInvalidResetToken, "reset token is empty")
+	}
+
+	if newPassword == "" {
+		return apperrors.ErrPasswordIsEmpty
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.MinCost)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate password hash")
+	}
+
+	user, err := u.userStore.ResetPassword(ctx, hashToken(token), string(passwordHash), u.now())
+	if err != nil {
+		return errors.Wrap(err, "failed to reset password")
+	}
+
+	u.logger.Info().Str("login", user.Login).Msg("password is reset")
+
+	return nil
+}
+
+// Run periodically removes the expired tokens.
+func (u *UserService) Run() {
+	ticker := time.NewTicker(cleanupInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				u.cleanup()
+			case <-u.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (u *UserService) Stop() {
+	close(u.stopChan)
+}
+
+func (u *UserService) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := u.tokenStore.DeleteExpired(ctx, u.now()); err != nil {
+		u.logger.Error().Err(err).Msg("failed to delete expired tokens")
+	}
+}
+
+func (u *UserService) parseToken(token string) (accessClaims, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (any, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(u.secret), nil
-	})
+	}, jwt.WithTimeFunc(u.now))
+
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return claims, errors.Wrap(apperrors.ErrTokenExpired, err.Error())
+	case err != nil:
+		return claims, errors.Wrapf(apperrors.ErrAuthFailed, "failed to parse token: %s", err)
+	case claims.ExpiresAt == nil:
+		// The tokens issued before the expiration was introduced would work forever
+		return claims, errors.Wrap(apperrors.ErrAuthFailed, "token has no expiration time")
+	case claims.ID == "":
+		// A token without id couldn't be revoked
+		return claims, errors.Wrap(apperrors.ErrAuthFailed, "token has no id")
+	case claims.Login == "":
+		return claims, errors.Wrap(apperrors.ErrAuthFailed, "token has no login")
+	default:
+		return claims, nil
+	}
+}
+
+// issueTokens starts a new session of the user.
+func (u *UserService) issueTokens(ctx context.Context, user domain.User) (domain.Tokens, error) {
+	sessionID, err := randomString(16)
+	if err != nil {
+		return domain.Tokens{}, errors.Wrap(err, "failed to generate session id")
+	}
+
+	accessToken, expiresAt, err := u.generateJWT(user, sessionID)
+	if err != nil {
+		return domain.Tokens{}, err
+	}
 
+	refresh, refreshToken, err := u.newRefreshToken()
 	if err != nil {
-		return "", errors.Wrap(err, "failed to parse token")
+		return domain.Tokens{}, err
 	}
 
-	if claims, ok := t.Claims.(jwt.MapClaims); ok && t.Valid {
-		return claims["login"].(string), nil
-	} else {
-		return "", errors.New("failed to parse token")
+	refresh.UserID = user.ID
+	refresh.SessionID = sessionID
+	if err := u.tokenStore.CreateRefreshToken(ctx, refresh); err != nil {
+		return domain.Tokens{}, errors.Wrapf(err, "failed to save refresh token of user %s", user.Login)
 	}
+
+	return domain.Tokens{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresAt: expiresAt}, nil
 }
 
-func (u *UserService) generateJWT(login string) (string, error) {
-	// Create a new token object, specifying signing method and the claims
-	// you would like it to contain.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"login":      login,
-		"created_at": time.Now().Unix(),
+func (u *UserService) generateJWT(user domain.User, sessionID string) (string, time.Time, error) {
+	now := u.now()
+	expiresAt := now.Add(u.options.AccessTokenTTL)
+
+	id, err := randomString(16)
+	if err != nil {
+		return "", expiresAt, errors.Wrap(err, "failed to generate token id")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims{
+		Login:     user.Login,
+		Version:   user.TokenVersion,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
 	})
 
 	// Sign and get the complete encoded token as a string using the secret
 	tokenString, err := token.SignedString([]byte(u.secret))
 	if err != nil {
-		return "", errors.Wrap(err, "failed to create a token")
+		return "", expiresAt, errors.Wrap(err, "failed to create a token")
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
+}
+
+// newRefreshToken generates a random refresh token. The token is given to the user
+// and only its hash is stored.
+func (u *UserService) newRefreshToken() (domain.RefreshToken, string, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return domain.RefreshToken{}, "", errors.Wrap(err, "failed to generate refresh token")
+	}
+
+	now := u.now()
+	return domain.RefreshToken{
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.options.RefreshTokenTTL),
+	}, token, nil
+}
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -6,17 +6,32 @@ import (
 	"gophermart/internal/core/domain"
 	"gophermart/internal/core/services/logging"
 	mock "gophermart/mocks/core/ports"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	testNow     = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	testOptions = Options{
+		AccessTokenTTL:   15 * time.Minute,
+		RefreshTokenTTL:  24 * time.Hour,
+		PasswordResetTTL: time.Hour,
+	}
 )
 
 func TestUserService_RegisterUser(t *testing.T) {
 	type args struct {
-		login    string
-		password string
+		login        string
+		password     string
+		referralCode string
 	}
 	type want struct {
 		errorIs error
@@ -27,11 +42,16 @@ func TestUserService_RegisterUser(t *testing.T) {
 		returns error
 		times   int
 	}
+	type referralCall struct {
+		referrer domain.User
+		returns  error
+	}
 	tests := []struct {
-		name      string
-		args      args
-		storeCall *storeCall
-		want      want
+		name         string
+		args         args
+		referralCall *referralCall
+		storeCall    *storeCall
+		want         want
 	}{
 		{
 			name: "empty login",
@@ -84,16 +104,57 @@ func TestUserService_RegisterUser(t *testing.T) {
 			},
 			want: want{
 				errorIs: nil,
+				token:   true,
+			},
+		},
+		{
+			name: "unknown referral code",
+			args: args{
+				login:        "login",
+				password:     "password",
+				referralCode: "ABC",
+			},
+			referralCall: &referralCall{returns: errors.Wrap(apperrors.ErrNoSuchReferralCode, "test")},
+			want: want{
+				errorIs: apperrors.ErrNoSuchReferralCode,
 				token:   false,
 			},
 		},
+		{
+			name: "success case with referral code",
+			args: args{
+				login:        "login",
+				password:     "password",
+				referralCode: "ABC",
+			},
+			referralCall: &referralCall{referrer: domain.User{ID: 2, Login: "referrer"}},
+			storeCall: &storeCall{
+				args:    []interface{}{gomock.Any(), "login", gomock.Any()},
+				returns: nil,
+				times:   1,
+			},
+			want: want{
+				errorIs: nil,
+				token:   true,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logService := logging.New()
 			ctrl := gomock.NewController(t)
 			userStore := mock.NewMockUserStore(ctrl)
-			userService := New("secret", logService, userStore)
+			accrualRules := mock.NewMockAccrualRules(ctrl)
+			referralService := mock.NewMockReferralService(ctrl)
+			tokenStore := mock.NewMockTokenStore(ctrl)
+			userService := New("secret", logService, userStore, tokenStore, accrualRules, referralService, nil, testOptions)
+
+			if tt.referralCall != nil {
+				referralService.EXPECT().
+					CheckReferralCode(gomock.Any(), tt.args.referralCode).
+					Return(tt.referralCall.referrer, tt.referralCall.returns).
+					Times(1)
+			}
 
 			if tt.storeCall != nil {
 				userStore.
@@ -103,14 +164,29 @@ func TestUserService_RegisterUser(t *testing.T) {
 					Times(tt.storeCall.times)
 			}
 
-			token, err := userService.RegisterUser(context.Background(), tt.args.login, tt.args.password)
+			// The new user gets the signup bonus and the first session
+			if tt.storeCall != nil && tt.storeCall.returns == nil {
+				user := domain.User{ID: 1, Login: tt.args.login}
+				userStore.EXPECT().GetUser(gomock.Any(), tt.args.login).Return(user, nil).Times(1)
+				accrualRules.EXPECT().GrantSignupBonus(gomock.Any(), user, gomock.Any()).Return(nil).Times(1)
+				tokenStore.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+				if tt.referralCall != nil {
+					referralService.EXPECT().AddReferral(gomock.Any(), tt.referralCall.referrer, user).Return(nil).Times(1)
+				}
+			}
+
+			tokens, err := userService.RegisterUser(
+				context.Background(), tt.args.login, tt.args.password, tt.args.referralCode,
+			)
 			if tt.want.errorIs != nil {
 				assert.ErrorIs(t, err, tt.want.errorIs)
 			} else {
 				assert.NoError(t, err)
 			}
 			if tt.want.token {
-				assert.NotEmptyf(t, token, "token shouldn't be empty")
+				assert.NotEmptyf(t, tokens.AccessToken, "token shouldn't be empty")
+				assert.NotEmptyf(t, tokens.RefreshToken, "refresh token shouldn't be empty")
 			}
 		})
 	}
@@ -199,7 +275,16 @@ func TestUserService_LoginUser(t *testing.T) {
 			logService := logging.New()
 			ctrl := gomock.NewController(t)
 			userStore := mock.NewMockUserStore(ctrl)
-			userService := New("secret", logService, userStore)
+			userService := New(
+				"secret",
+				logService,
+				userStore,
+				mock.NewMockTokenStore(ctrl),
+				mock.NewMockAccrualRules(ctrl),
+				mock.NewMockReferralService(ctrl),
+				nil,
+				testOptions,
+			)
 
 			if tt.storeCall != nil {
 				userStore.
@@ -209,15 +294,289 @@ func TestUserService_LoginUser(t *testing.T) {
 					Times(tt.storeCall.times)
 			}
 
-			token, err := userService.LoginUser(context.Background(), tt.args.login, tt.args.password)
+			tokens, err := userService.LoginUser(context.Background(), tt.args.login, tt.args.password)
 			if tt.want.errorIs != nil {
 				assert.ErrorIs(t, err, tt.want.errorIs)
 			} else {
 				assert.NoError(t, err)
 			}
 			if tt.want.token {
-				assert.NotEmptyf(t, token, "token shouldn't be empty")
+				assert.NotEmptyf(t, tokens.AccessToken, "token shouldn't be empty")
 			}
 		})
 	}
 }
+
+func TestUserService_AuthenticateUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userStore := mock.NewMockUserStore(ctrl)
+	tokenStore := mock.NewMockTokenStore(ctrl)
+	userService := New("secret", logging.New(), userStore, tokenStore, nil, nil, nil, testOptions)
+	userService.now = func() time.Time { return testNow }
+
+	token, _, err := userService.generateJWT(domain.User{ID: 1, Login: "user"}, "session")
+	require.NoError(t, err)
+
+	// Tokens issued before the expiration was introduced
+	noExpiry, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"login":      "user",
+		"created_at": testNow.Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		user    *domain.User
+		revoked bool
+		wantErr error
+	}{
+		{name: "garbage", token: "abc", now: testNow, wantErr: apperrors.ErrAuthFailed},
+		{name: "no expiration", token: noExpiry, now: testNow, wantErr: apperrors.ErrAuthFailed},
+		{name: "expired", token: token, now: testNow.Add(16 * time.Minute), wantErr: apperrors.ErrTokenExpired},
+		{
+			name:    "all sessions are logged out",
+			token:   token,
+			now:     testNow,
+			user:    &domain.User{ID: 1, Login: "user", TokenVersion: 1},
+			wantErr: apperrors.ErrTokenRevoked,
+		},
+		{
+			name:    "session is logged out",
+			token:   token,
+			now:     testNow,
+			user:    &domain.User{ID: 1, Login: "user"},
+			revoked: true,
+			wantErr: apperrors.ErrTokenRevoked,
+		},
+		{
+			name:  "success case",
+			token: token,
+			now:   testNow.Add(14 * time.Minute),
+			user:  &domain.User{ID: 1, Login: "user"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService.now = func() time.Time { return tt.now }
+
+			if tt.user != nil {
+				userStore.EXPECT().GetUser(gomock.Any(), "user").Return(*tt.user, nil).Times(1)
+				if tt.user.TokenVersion == 0 {
+					tokenStore.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Return(tt.revoked, nil).Times(1)
+				}
+			}
+
+			user, err := userService.AuthenticateUser(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "user", user.Login)
+		})
+	}
+}
+
+func TestUserService_Logout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tokenStore := mock.NewMockTokenStore(ctrl)
+	userService := New("secret", logging.New(), nil, tokenStore, nil, nil, nil, testOptions)
+	userService.now = func() time.Time { return testNow }
+
+	user := domain.User{ID: 1, Login: "user"}
+	token, expiresAt, err := userService.generateJWT(user, "session")
+	require.NoError(t, err)
+
+	tokenStore.EXPECT().
+		RevokeSession(gomock.Any(), gomock.Any(), testNow).
+		DoAndReturn(func(_ context.Context, session domain.Session, _ time.Time) error {
+			assert.Equal(t, 1, session.UserID)
+			assert.Equal(t, "session", session.SessionID)
+			assert.NotEmpty(t, session.TokenID)
+			assert.True(t, expiresAt.Equal(session.ExpiresAt))
+			return nil
+		}).
+		Times(1)
+
+	assert.NoError(t, userService.Logout(context.Background(), &user, token))
+
+	tokenStore.EXPECT().RevokeAllSessions(gomock.Any(), 1, testNow).Return(nil).Times(1)
+
+	assert.NoError(t, userService.LogoutAll(context.Background(), &user))
+}
+
+func TestUserService_RefreshTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tokenStore := mock.NewMockTokenStore(ctrl)
+	userService := New("secret", logging.New(), nil, tokenStore, nil, nil, nil, testOptions)
+	userService.now = func() time.Time { return testNow }
+
+	_, err := userService.RefreshTokens(context.Background(), "")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRefreshToken)
+
+	var next domain.RefreshToken
+	tokenStore.EXPECT().
+		RotateRefreshToken(gomock.Any(), hashToken("old"), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ string,
+			token domain.RefreshToken,
+		) (domain.User, domain.RefreshToken, error) {
+			next = token
+			token.UserID = 1
+			token.SessionID = "session"
+			return domain.User{ID: 1, Login: "user"}, token, nil
+		}).
+		Times(1)
+
+	tokens, err := userService.RefreshTokens(context.Background(), "old")
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.Equal(t, testNow.Add(testOptions.AccessTokenTTL), tokens.ExpiresAt)
+	assert.Equal(t, hashToken(tokens.RefreshToken), next.TokenHash)
+	assert.Equal(t, testNow.Add(testOptions.RefreshTokenTTL), next.ExpiresAt)
+
+	// The new access token belongs to the same session
+	claims, err := userService.parseToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "session", claims.SessionID)
+
+	tokenStore.EXPECT().
+		RotateRefreshToken(gomock.Any(), hashToken("reused"), gomock.Any()).
+		Return(domain.User{}, domain.RefreshToken{}, errors.Wrap(apperrors.ErrRefreshTokenReused, "test")).
+		Times(1)
+
+	_, err = userService.RefreshTokens(context.Background(), "reused")
+	assert.ErrorIs(t, err, apperrors.ErrRefreshTokenReused)
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("old"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := domain.User{ID: 1, Login: "user", Password: string(oldHash)}
+
+	tests := []struct {
+		name        string
+		oldPassword string
+		newPassword string
+		wantErr     error
+	}{
+		{name: "empty password", oldPassword: "old", newPassword: "", wantErr: apperrors.ErrPasswordIsEmpty},
+		{name: "wrong old password", oldPassword: "wrong", newPassword: "new", wantErr: apperrors.ErrPasswordIncorrect},
+		{name: "success case", oldPassword: "old", newPassword: "new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			userStore := mock.NewMockUserStore(ctrl)
+			tokenStore := mock.NewMockTokenStore(ctrl)
+			userService := New("secret", logging.New(), userStore, tokenStore, nil, nil, nil, testOptions)
+			userService.now = func() time.Time { return testNow }
+
+			if tt.wantErr == nil {
+				userStore.EXPECT().
+					UpdatePassword(gomock.Any(), 1, gomock.Any(), testNow).
+					DoAndReturn(func(_ context.Context, _ int, passwordHash string, _ time.Time) error {
+						assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(tt.newPassword)))
+						return nil
+					}).
+					Times(1)
+				userStore.EXPECT().
+					GetUser(gomock.Any(), "user").
+					Return(domain.User{ID: 1, Login: "user", TokenVersion: 1}, nil).
+					Times(1)
+				tokenStore.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			}
+
+			tokens, err := userService.ChangePassword(context.Background(), &user, tt.oldPassword, tt.newPassword)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// The current session continues with the new token version
+			claims, err := userService.parseToken(tokens.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, 1, claims.Version)
+		})
+	}
+}
+
+func TestUserService_PasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userStore := mock.NewMockUserStore(ctrl)
+	notifier := mock.NewMockNotifier(ctrl)
+	userService := New("secret", logging.New(), userStore, nil, nil, nil, notifier, testOptions)
+	userService.now = func() time.Time { return testNow }
+
+	assert.ErrorIs(t, userService.RequestPasswordReset(context.Background(), ""), apperrors.ErrLoginIsEmpty)
+
+	// Unknown users aren't reported
+	userStore.EXPECT().GetUser(gomock.Any(), "unknown").Return(domain.User{}, errors.New("no rows")).Times(1)
+	assert.NoError(t, userService.RequestPasswordReset(context.Background(), "unknown"))
+
+	var reset domain.PasswordReset
+	var notification domain.Notification
+	userStore.EXPECT().GetUser(gomock.Any(), "user").Return(domain.User{ID: 1, Login: "user"}, nil).Times(1)
+	userStore.EXPECT().
+		CreatePasswordReset(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r domain.PasswordReset) error {
+			reset = r
+			return nil
+		}).
+		Times(1)
+	notifier.EXPECT().
+		Notify(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, n domain.Notification) error {
+			notification = n
+			return nil
+		}).
+		Times(1)
+
+	require.NoError(t, userService.RequestPasswordReset(context.Background(), "user"))
+	assert.Equal(t, 1, reset.UserID)
+	assert.Equal(t, testNow.Add(time.Hour), reset.ExpiresAt)
+	assert.Equal(t, "user", notification.Login)
+
+	// The token is only sent to the user, the hash of it is stored
+	var token string
+	for _, field := range strings.Fields(notification.Body) {
+		if hashToken(field) == reset.TokenHash {
+			token = field
+		}
+	}
+	require.NotEmpty(t, token, "notification should contain the reset token")
+
+	err := userService.ResetPassword(context.Background(), "", "new")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidResetToken)
+
+	err = userService.ResetPassword(context.Background(), token, "")
+	assert.ErrorIs(t, err, apperrors.ErrPasswordIsEmpty)
+
+	userStore.EXPECT().
+		ResetPassword(gomock.Any(), reset.TokenHash, gomock.Any(), testNow).
+		Return(domain.User{ID: 1, Login: "user"}, nil).
+		Times(1)
+	assert.NoError(t, userService.ResetPassword(context.Background(), token, "new"))
+
+	userStore.EXPECT().
+		ResetPassword(gomock.Any(), reset.TokenHash, gomock.Any(), testNow).
+		Return(domain.User{}, errors.Wrap(apperrors.ErrInvalidResetToken, "test")).
+		Times(1)
+	err = userService.ResetPassword(context.Background(), token, "new")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidResetToken)
+}
+
+func TestUserService_PasswordResetDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userService := New("secret", logging.New(), mock.NewMockUserStore(ctrl), nil, nil, nil, nil, testOptions)
+
+	err := userService.RequestPasswordReset(context.Background(), "user")
+	assert.ErrorIs(t, err, apperrors.ErrPasswordResetDisabled)
+
+	err = userService.ResetPassword(context.Background(), "token", "new")
+	assert.ErrorIs(t, err, apperrors.ErrPasswordResetDisabled)
+}
@@ -0,0 +1,209 @@
+package holdstore
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/ledgerstore"
+	"gophermart/internal/core/stores/pgerrors"
+	"gophermart/internal/core/stores/withdrawstore"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type HoldStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *HoldStore {
+	return &HoldStore{db: db}
+}
+
+// CreateHold reserves the points of the user. The balance is locked the same way as for a withdrawal,
+// so the reserved points can't be spent twice or exceed the daily limit.
+func (h *HoldStore) CreateHold(
+	ctx context.Context,
+	hold domain.Hold,
+	limits domain.WithdrawalLimits,
+) (domain.Hold, error) {
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return hold, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	balance, err := ledgerstore.LockBalance(ctx, tx, hold.UserID)
+	if err != nil {
+		return hold, err
+	}
+
+	if balance.Current < hold.Amount {
+		return hold, errors.Wrapf(apperrors.ErrNotEnoughMoney, "balance %s is less than %s", balance.Current, hold.Amount)
+	}
+
+	if err := withdrawstore.CheckDailyLimit(ctx, tx, hold.UserID, hold.Amount, limits, hold.CreatedAt); err != nil {
+		return hold, err
+	}
+
+	if err := tx.GetContext(ctx, &hold.ID, `
+		insert into point_holds(user_id, order_number, amount, status, created_at, expires_at)
+		values ($1, $2, $3, $4, $5, $6)
+		returning id
+	`, hold.UserID, hold.OrderNumber, hold.Amount, hold.Status, hold.CreatedAt, hold.ExpiresAt); err != nil {
+		if pgerrors.IsUniqueViolation(err) {
+			return hold, errors.Wrapf(apperrors.ErrHoldAlreadyExists, "order '%s'", hold.OrderNumber)
+		}
+		return hold, errors.Wrapf(err, "failed to insert hold for order '%s' into a database", hold.OrderNumber)
+	}
+
+	if _, err := ledgerstore.Post(ctx, tx, domain.HoldTransaction(hold)); err != nil {
+		return hold, errors.Wrapf(err, "failed to post hold for order '%s'", hold.OrderNumber)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return hold, errors.Wrap(err, "unable to commit")
+	}
+
+	return hold, nil
+}
+
+// CaptureHold withdraws the reserved points. The withdrawn is created within the same transaction.
+func (h *HoldStore) CaptureHold(ctx context.Context, userID int, holdID int, now time.Time) (domain.Hold, error) {
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.Hold{}, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	hold, err := lockActiveHold(ctx, tx, userID, holdID, now)
+	if err != nil {
+		return hold, err
+	}
+
+	withdrawn := domain.Withdrawn{
+		OrderNumber: hold.OrderNumber,
+		Sum:         hold.Amount,
+		ProcessedAt: now,
+		UserID:      hold.UserID,
+		Status:      domain.WithdrawnStatusPending,
+	}
+	if err := withdrawstore.Insert(ctx, tx, &withdrawn); err != nil {
+		return hold, err
+	}
+
+	if _, err := ledgerstore.Post(ctx, tx, domain.CaptureTransaction(withdrawn)); err != nil {
+		return hold, errors.Wrapf(err, "failed to post capture of hold %d", holdID)
+	}
+
+	if err := finishHold(ctx, tx, &hold, domain.HoldStatusCaptured, now); err != nil {
+		return hold, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return hold, errors.Wrap(err, "unable to commit")
+	}
+
+	return hold, nil
+}
+
+// ReleaseHold gives the reserved points back to the user.
+func (h *HoldStore) ReleaseHold(ctx context.Context, userID int, holdID int, now time.Time) (domain.Hold, error) {
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.Hold{}, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	hold, err := lockActiveHold(ctx, tx, userID, holdID, now)
+	if err != nil {
+		return hold, err
+	}
+
+	if err := release(ctx, tx, &hold, domain.HoldStatusReleased, now); err != nil {
+		return hold, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return hold, errors.Wrap(err, "unable to commit")
+	}
+
+	return hold, nil
+}
+
+// ExpireHolds gives back the points of the holds which weren't captured in time.
+// The holds locked by other instances are skipped, they are expired on the next run.
+func (h *HoldStore) ExpireHolds(ctx context.Context, now time.Time, limit int) (int, error) {
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	var holds []domain.Hold
+	if err := tx.SelectContext(ctx, &holds, `
+		select * from point_holds
+		where status=$1 and expires_at<=$2
+		order by expires_at
+		limit $3
+		for update skip locked
+	`, domain.HoldStatusActive, now, limit); err != nil {
+		return 0, errors.Wrap(err, "failed to get expired holds")
+	}
+
+	for i := range holds {
+		if err := release(ctx, tx, &holds[i], domain.HoldStatusExpired, now); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "unable to commit")
+	}
+
+	return len(holds), nil
+}
+
+func lockActiveHold(ctx context.Context, tx *sqlx.Tx, userID int, holdID int, now time.Time) (domain.Hold, error) {
+	var hold domain.Hold
+	err := tx.GetContext(ctx, &hold, `
+		select * from point_holds
+		where id=$1 and user_id=$2
+		for update
+	`, holdID, userID)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return hold, errors.Wrapf(apperrors.ErrNoSuchHold, "hold %d", holdID)
+	case err != nil:
+		return hold, errors.Wrapf(err, "failed to lock hold %d", holdID)
+	case !hold.IsActive(now):
+		return hold, errors.Wrapf(apperrors.ErrHoldNotActive, "hold %d is %s", holdID, hold.Status)
+	default:
+		return hold, nil
+	}
+}
+
+func release(ctx context.Context, tx *sqlx.Tx, hold *domain.Hold, status domain.HoldStatus, now time.Time) error {
+	if _, err := ledgerstore.Post(ctx, tx, domain.ReleaseTransaction(*hold, now)); err != nil {
+		return errors.Wrapf(err, "failed to post release of hold %d", hold.ID)
+	}
+
+	return finishHold(ctx, tx, hold, status, now)
+}
+
+func finishHold(ctx context.Context, tx *sqlx.Tx, hold *domain.Hold, status domain.HoldStatus, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, `
+		update point_holds
+		set status=$1, finished_at=$2
+		where id=$3
+	`, status, now, hold.ID); err != nil {
+		return errors.Wrapf(err, "failed to finish hold %d", hold.ID)
+	}
+
+	hold.Status = status
+	hold.FinishedAt = &now
+	return nil
+}
@@ -0,0 +1,99 @@
+package holdstore
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/ledgerstore"
+	"gophermart/internal/core/stores/storetest"
+	"gophermart/internal/core/stores/withdrawstore"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test needs a real database, see storetest.
+func TestHoldStore_DailyLimit(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	userID := storetest.NewUser(t, dbx)
+	require.NoError(t, ledgerstore.New(dbx).PostTransaction(ctx, domain.PromoTransaction(domain.PromoRedemption{
+		ID:         1,
+		Code:       "test",
+		UserID:     userID,
+		Amount:     10000,
+		RedeemedAt: time.Now(),
+	})))
+
+	holdStore := New(dbx)
+	withdrawStore := withdrawstore.New(dbx)
+	limits := domain.WithdrawalLimits{DailyAmount: 5000}
+
+	newHold := func(amount domain.Money) domain.Hold {
+		return domain.Hold{
+			UserID:      userID,
+			OrderNumber: fmt.Sprintf("%d-%d", userID, time.Now().UnixNano()),
+			Amount:      amount,
+			Status:      domain.HoldStatusActive,
+			CreatedAt:   time.Now(),
+			ExpiresAt:   time.Now().Add(time.Hour),
+		}
+	}
+
+	hold, err := holdStore.CreateHold(ctx, newHold(3000), limits)
+	require.NoError(t, err)
+
+	// The active hold counts towards the daily limit
+	err = withdrawStore.AddNewWithdrawn(ctx, fmt.Sprintf("%d-withdrawn", userID), 2500, userID, limits)
+	assert.ErrorIs(t, err, apperrors.ErrWithdrawalLimitExceeded)
+
+	// The captured one as well
+	_, err = holdStore.CaptureHold(ctx, userID, hold.ID, time.Now())
+	require.NoError(t, err)
+
+	_, err = holdStore.CreateHold(ctx, newHold(2500), limits)
+	assert.ErrorIs(t, err, apperrors.ErrWithdrawalLimitExceeded)
+
+	_, err = holdStore.CreateHold(ctx, newHold(2000), limits)
+	assert.NoError(t, err)
+}
+
+func TestHoldStore_ReverseCaptured(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	userID := storetest.NewUser(t, dbx)
+	ledgerStore := ledgerstore.New(dbx)
+	require.NoError(t, ledgerStore.PostTransaction(ctx, domain.PromoTransaction(domain.PromoRedemption{
+		ID:         1,
+		Code:       "test",
+		UserID:     userID,
+		Amount:     10000,
+		RedeemedAt: time.Now(),
+	})))
+
+	hold, err := New(dbx).CreateHold(ctx, domain.Hold{
+		UserID:      userID,
+		OrderNumber: fmt.Sprintf("%d-captured", userID),
+		Amount:      3000,
+		Status:      domain.HoldStatusActive,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}, domain.WithdrawalLimits{})
+	require.NoError(t, err)
+
+	_, err = New(dbx).CaptureHold(ctx, userID, hold.ID, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, withdrawstore.New(dbx).ReverseWithdrawn(ctx, hold.OrderNumber, "order is cancelled"))
+
+	// The points return to the lot of the promo code they were held from
+	lots, err := ledgerStore.GetLots(ctx, userID, time.Now())
+	require.NoError(t, err)
+	require.Len(t, lots, 1)
+	assert.Equal(t, "promo:1", lots[0].TransactionID)
+	assert.Equal(t, domain.Money(10000), lots[0].Amount)
+}
@@ -0,0 +1,94 @@
+package idempotencystore
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type IdempotencyStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *IdempotencyStore {
+	return &IdempotencyStore{db: db}
+}
+
+// CreateRecord saves the record unless there is a not expired record with the same key.
+// In the latter case the existing record is returned and the second result is false.
+// The records left in progress since before abandonedBefore are replaced as the expired ones.
+func (i *IdempotencyStore) CreateRecord(
+	ctx context.Context,
+	record domain.IdempotencyRecord,
+	abandonedBefore time.Time,
+) (domain.IdempotencyRecord, bool, error) {
+	var created []int
+	if err := i.db.SelectContext(ctx, &created, `
+		insert into idempotency_keys(user_id, key, request_hash, created_at, expires_at)
+		values ($1, $2, $3, $4, $5)
+		on conflict (user_id, key) do update
+		set request_hash=excluded.request_hash,
+			status_code=0,
+			content_type='',
+			response_body='',
+			created_at=excluded.created_at,
+			expires_at=excluded.expires_at
+		where idempotency_keys.expires_at <= excluded.created_at
+			or (idempotency_keys.status_code = 0 and idempotency_keys.created_at <= $6)
+		returning user_id
+	`, record.UserID, record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt, abandonedBefore); err != nil {
+		return record, false, errors.Wrapf(err, "failed to create idempotency key '%s'", record.Key)
+	}
+
+	if len(created) > 0 {
+		return record, true, nil
+	}
+
+	var existing domain.IdempotencyRecord
+	if err := i.db.GetContext(ctx, &existing, `
+		select * from idempotency_keys
+		where user_id=$1 and key=$2
+	`, record.UserID, record.Key); err != nil {
+		return existing, false, errors.Wrapf(err, "failed to get idempotency key '%s'", record.Key)
+	}
+
+	return existing, false, nil
+}
+
+// CompleteRecord saves the response to the request.
+func (i *IdempotencyStore) CompleteRecord(ctx context.Context, record domain.IdempotencyRecord) error {
+	if _, err := i.db.ExecContext(ctx, `
+		update idempotency_keys
+		set status_code=$1, content_type=$2, response_body=$3
+		where user_id=$4 and key=$5
+	`, record.StatusCode, record.ContentType, record.Body, record.UserID, record.Key); err != nil {
+		return errors.Wrapf(err, "failed to complete idempotency key '%s'", record.Key)
+	}
+
+	return nil
+}
+
+func (i *IdempotencyStore) DeleteRecord(ctx context.Context, userID int, key string) error {
+	if _, err := i.db.ExecContext(ctx, `
+		delete from idempotency_keys
+		where user_id=$1 and key=$2
+	`, userID, key); err != nil {
+		return errors.Wrapf(err, "failed to delete idempotency key '%s'", key)
+	}
+
+	return nil
+}
+
+func (i *IdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	if _, err := i.db.ExecContext(ctx, `
+		delete from idempotency_keys
+		where expires_at <= $1
+	`, now); err != nil {
+		return errors.Wrap(err, "failed to delete expired idempotency keys")
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package ledgerstore
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/core/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type LedgerStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *LedgerStore {
+	return &LedgerStore{db: db}
+}
+
+func (l *LedgerStore) GetBalance(ctx context.Context, userID int) (domain.UserBalance, error) {
+	var balance domain.UserBalance
+	err := l.db.GetContext(ctx, &balance, `
+		select current, held, withdrawn from balances
+		where user_id=$1
+	`, userID)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return domain.UserBalance{}, nil
+	case err != nil:
+		return balance, errors.Wrapf(err, "failed to get balance for user with id %d", userID)
+	default:
+		return balance, nil
+	}
+}
+
+// GetHistory returns the postings to the points account of the user.
+func (l *LedgerStore) GetHistory(ctx context.Context, userID int) ([]domain.LedgerEntry, error) {
+	var entries []domain.LedgerEntry
+	if err := l.db.SelectContext(ctx, &entries, `
+		select * from ledger_entries
+		where user_id=$1 and account=$2
+		order by created_at, id
+	`, userID, domain.LedgerAccountPoints); err != nil {
+		return entries, errors.Wrapf(err, "failed to get ledger history for user with id %d", userID)
+	}
+
+	return entries, nil
+}
+
+// GetLots returns the unspent lots of the user earned before the given moment, oldest first.
+func (l *LedgerStore) GetLots(ctx context.Context, userID int, earnedBefore time.Time) ([]domain.PointsLot, error) {
+	return getLots(ctx, l.db, userID, earnedBefore)
+}
+
+// GetExpiredPointsOwners returns the users who have unspent lots earned before the given moment.
+// Only their points may have expired.
+func (l *LedgerStore) GetExpiredPointsOwners(ctx context.Context, earnedBefore time.Time) ([]int, error) {
+	var userIDs []int
+	if err := l.db.SelectContext(ctx, &userIDs, `
+		select distinct user_id from point_lots
+		where remaining>0 and earned_at<=$1
+	`, earnedBefore); err != nil {
+		return userIDs, errors.Wrap(err, "failed to get owners of expired points")
+	}
+
+	return userIDs, nil
+}
+
+// ExpirePoints burns the expired points of the user. The balance is locked meanwhile,
+// so the points can't be spent and expired at the same time.
+func (l *LedgerStore) ExpirePoints(
+	ctx context.Context,
+	userID int,
+	policy domain.ExpiryPolicy,
+	now time.Time,
+) (domain.Money, error) {
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := LockBalance(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+
+	lots, err := getLots(ctx, tx, userID, policy.EarnedBefore(now))
+	if err != nil {
+		return 0, err
+	}
+
+	// The expired lots are the oldest ones, so the expiration spends exactly the expired lot
+	var expired domain.Money
+	for _, lot := range policy.ExpiredLots(lots, now) {
+		posted, err := Post(ctx, tx, domain.ExpiryTransaction(userID, lot, now))
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to expire points of user with id %d", userID)
+		}
+		if !posted {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			update point_lots set expirations=expirations+1
+			where id=$1
+		`, lot.ID); err != nil {
+			return 0, errors.Wrapf(err, "failed to count expiration of points lot with id %d", lot.ID)
+		}
+		expired += lot.Amount
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "unable to commit")
+	}
+
+	return expired, nil
+}
+
+// PostTransaction writes the transaction to the ledger in its own database transaction.
+func (l *LedgerStore) PostTransaction(ctx context.Context, transaction domain.LedgerTransaction) error {
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := Post(ctx, tx, transaction); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit")
+	}
+
+	return nil
+}
+
+// LockBalance locks the balance row of the user until the end of the given database transaction,
+// so nobody else can spend the same points concurrently.
+func LockBalance(ctx context.Context, tx *sqlx.Tx, userID int) (domain.UserBalance, error) {
+	var balance domain.UserBalance
+
+	if _, err := tx.ExecContext(ctx, `
+		insert into balances(user_id, current, withdrawn, updated_at)
+		values ($1, 0, 0, $2)
+		on conflict (user_id) do nothing
+	`, userID, time.Now()); err != nil {
+		return balance, errors.Wrapf(err, "failed to create balance for user with id %d", userID)
+	}
+
+	if err := tx.GetContext(ctx, &balance, `
+		select current, held, withdrawn from balances
+		where user_id=$1
+		for update
+	`, userID); err != nil {
+		return balance, errors.Wrapf(err, "failed to lock balance for user with id %d", userID)
+	}
+
+	return balance, nil
+}
+
+// Post writes the transaction to the ledger and updates the balance of the user within the given
+// database transaction. Posting of the transaction which is already in the ledger does nothing
+// and reports false.
+func Post(ctx context.Context, tx *sqlx.Tx, transaction domain.LedgerTransaction) (bool, error) {
+	if transaction.Amount <= 0 {
+		return false, errors.Errorf("ledger transaction %s should have positive amount", transaction.ID)
+	}
+
+	for i, entry := range transaction.Entries() {
+		res, err := tx.ExecContext(ctx, `
+			insert into ledger_entries(
+				transaction_id, user_id, account, direction, amount, reason, reference, created_at
+			)
+			values ($1, $2, $3, $4, $5, $6, $7, $8)
+			on conflict (transaction_id, user_id, account) do nothing
+		`,
+			entry.TransactionID,
+			entry.UserID,
+			entry.Account,
+			entry.Direction,
+			entry.Amount,
+			entry.Reason,
+			entry.Reference,
+			entry.CreatedAt,
+		)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to insert ledger entry of transaction %s", transaction.ID)
+		}
+
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get number of inserted ledger entries")
+		}
+
+		if inserted == 0 {
+			if i == 0 {
+				return false, nil
+			}
+			return false, errors.Errorf("ledger transaction %s is posted partially", transaction.ID)
+		}
+
+		if err := updateBalance(ctx, tx, entry); err != nil {
+			return false, err
+		}
+
+		if err := updateLots(ctx, tx, transaction, entry); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// updateBalance keeps the balance row in sync with the ledger.
+func updateBalance(ctx context.Context, tx *sqlx.Tx, entry domain.LedgerEntry) error {
+	var current, held, withdrawn domain.Money
+	switch entry.Account {
+	case domain.LedgerAccountPoints:
+		current = entry.SignedAmount()
+	case domain.LedgerAccountHeld:
+		held = entry.SignedAmount()
+	case domain.LedgerAccountWithdrawals:
+		withdrawn = entry.SignedAmount()
+	default:
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		insert into balances(user_id, current, held, withdrawn, updated_at)
+		values ($1, $2, $3, $4, $5)
+		on conflict (user_id) do update
+		set current=balances.current + excluded.current,
+			held=balances.held + excluded.held,
+			withdrawn=balances.withdrawn + excluded.withdrawn,
+			updated_at=excluded.updated_at
+	`, entry.UserID, current, held, withdrawn, time.Now()); err != nil {
+		return errors.Wrapf(err, "failed to update balance for user with id %d", entry.UserID)
+	}
+
+	return nil
+}
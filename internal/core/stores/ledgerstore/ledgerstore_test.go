@@ -0,0 +1,184 @@
+package ledgerstore
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/storetest"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests need a real database, see storetest.
+func TestPost_Duplicate(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	userID := storetest.NewUser(t, dbx)
+	transaction := newTestBonus(userID, 1000)
+
+	posted, err := post(t, dbx, transaction)
+	require.NoError(t, err)
+	assert.True(t, posted)
+
+	posted, err = post(t, dbx, transaction)
+	require.NoError(t, err)
+	assert.False(t, posted)
+
+	balance, err := New(dbx).GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserBalance{Current: 1000}, balance)
+
+	entries, err := New(dbx).GetHistory(ctx, userID)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPost_Partial(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	userID := storetest.NewUser(t, dbx)
+	transaction := newTestBonus(userID, 1000)
+
+	_, err := post(t, dbx, transaction)
+	require.NoError(t, err)
+
+	// The debit leg is new, but the credit leg is already in the ledger
+	conflicting := transaction
+	conflicting.From = domain.LedgerAccountAccruals
+	posted, err := post(t, dbx, conflicting)
+	assert.ErrorContains(t, err, "posted partially")
+	assert.False(t, posted)
+
+	balance, err := New(dbx).GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserBalance{Current: 1000}, balance)
+
+	var accruals int
+	require.NoError(t, dbx.GetContext(ctx, &accruals, `
+		select count(*) from ledger_entries
+		where user_id=$1 and account=$2
+	`, userID, domain.LedgerAccountAccruals))
+	assert.Zero(t, accruals)
+}
+
+func TestExpirePoints(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	ledgerStore := New(dbx)
+	policy := domain.ExpiryPolicy{Months: 6}
+	now := time.Now()
+	userID := storetest.NewUser(t, dbx)
+
+	old := newTestBonus(userID, 1000)
+	old.CreatedAt = now.AddDate(0, -7, 0)
+	recent := newTestBonus(userID, 2000)
+	recent.CreatedAt = now.AddDate(0, -1, 0)
+	for _, transaction := range []domain.LedgerTransaction{old, recent} {
+		_, err := post(t, dbx, transaction)
+		require.NoError(t, err)
+	}
+
+	// The hold takes the oldest points, so only the rest of the old lot expires
+	hold := domain.Hold{ID: 1, UserID: userID, Amount: 600, CreatedAt: now.AddDate(0, 0, -1)}
+	_, err := post(t, dbx, domain.HoldTransaction(hold))
+	require.NoError(t, err)
+
+	expired, err := ledgerStore.ExpirePoints(ctx, userID, policy, now)
+	require.NoError(t, err)
+	assert.Equal(t, domain.Money(400), expired)
+
+	// The released points return to the old lot and expire as well
+	_, err = post(t, dbx, domain.ReleaseTransaction(hold, now))
+	require.NoError(t, err)
+
+	expired, err = ledgerStore.ExpirePoints(ctx, userID, policy, now)
+	require.NoError(t, err)
+	assert.Equal(t, domain.Money(600), expired)
+
+	expired, err = ledgerStore.ExpirePoints(ctx, userID, policy, now)
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+
+	balance, err := ledgerStore.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserBalance{Current: 2000}, balance)
+
+	lots, err := ledgerStore.GetLots(ctx, userID, now)
+	require.NoError(t, err)
+	require.Len(t, lots, 1)
+	assert.Equal(t, recent.ID, lots[0].TransactionID)
+	assert.Equal(t, domain.Money(2000), lots[0].Amount)
+
+	owners, err := ledgerStore.GetExpiredPointsOwners(ctx, policy.EarnedBefore(now))
+	require.NoError(t, err)
+	assert.NotContains(t, owners, userID)
+}
+
+func TestExpirePoints_Transfer(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	ledgerStore := New(dbx)
+	policy := domain.ExpiryPolicy{Months: 6}
+	now := time.Now()
+	senderID := storetest.NewUser(t, dbx)
+	recipientID := storetest.NewUser(t, dbx)
+
+	bonus := newTestBonus(senderID, 1000)
+	bonus.CreatedAt = now.AddDate(0, -7, 0)
+	_, err := post(t, dbx, bonus)
+	require.NoError(t, err)
+
+	// Passing the points back and forth doesn't make them younger
+	for _, transfer := range []domain.Transfer{
+		{ID: 1, SenderID: senderID, RecipientID: recipientID, Amount: 1000, CreatedAt: now.Add(-time.Hour)},
+		{ID: 2, SenderID: recipientID, RecipientID: senderID, Amount: 600, CreatedAt: now.Add(-time.Minute)},
+	} {
+		_, err := post(t, dbx, domain.TransferTransaction(transfer))
+		require.NoError(t, err)
+	}
+
+	for userID, amount := range map[int]domain.Money{senderID: 600, recipientID: 400} {
+		expired, err := ledgerStore.ExpirePoints(ctx, userID, policy, now)
+		require.NoError(t, err)
+		assert.Equal(t, amount, expired)
+	}
+}
+
+// -- Test helpers --
+
+func newTestBonus(userID int, amount domain.Money) domain.LedgerTransaction {
+	return domain.LedgerTransaction{
+		ID:        fmt.Sprintf("bonus:%d", time.Now().UnixNano()),
+		UserID:    userID,
+		From:      domain.LedgerAccountBonuses,
+		To:        domain.LedgerAccountPoints,
+		Amount:    amount,
+		Reason:    domain.LedgerReasonPromo,
+		CreatedAt: time.Now(),
+	}
+}
+
+// post posts the transaction in its own database transaction, which is committed only on success.
+func post(t *testing.T, dbx *sqlx.DB, transaction domain.LedgerTransaction) (bool, error) {
+	ctx := context.Background()
+
+	tx, err := dbx.BeginTxx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	posted, err := Post(ctx, tx, transaction)
+	if err != nil {
+		return posted, err
+	}
+
+	require.NoError(t, tx.Commit())
+	return posted, nil
+}
@@ -0,0 +1,190 @@
+package ledgerstore
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// updateLots keeps the lots of the points account in sync with the ledger. The points are spent
+// oldest first, the returned points go back to the lots they were spent from, and the points
+// moved to another user keep their age.
+func updateLots(ctx context.Context, tx *sqlx.Tx, transaction domain.LedgerTransaction, entry domain.LedgerEntry) error {
+	if entry.Account != domain.LedgerAccountPoints {
+		return nil
+	}
+
+	switch {
+	case entry.Direction == domain.LedgerDirectionDebit:
+		return spendLots(ctx, tx, entry)
+	case transaction.Returns != "":
+		return returnLots(ctx, tx, transaction.Returns, entry)
+	case transaction.ToUserID != 0:
+		return moveLots(ctx, tx, transaction.UserID, entry)
+	default:
+		return addLot(ctx, tx, entry.UserID, entry.TransactionID, entry.CreatedAt, entry.Amount)
+	}
+}
+
+func addLot(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID int,
+	transactionID string,
+	earnedAt time.Time,
+	amount domain.Money,
+) error {
+	if _, err := tx.ExecContext(ctx, `
+		insert into point_lots(user_id, transaction_id, earned_at, amount, remaining)
+		values ($1, $2, $3, $4, $4)
+	`, userID, transactionID, earnedAt, amount); err != nil {
+		return errors.Wrapf(err, "failed to add points lot of transaction %s", transactionID)
+	}
+
+	return nil
+}
+
+// spendLots consumes the oldest lots and remembers how much of every lot is spent by the entry.
+func spendLots(ctx context.Context, tx *sqlx.Tx, entry domain.LedgerEntry) error {
+	var lots []domain.PointsLot
+	if err := tx.SelectContext(ctx, &lots, `
+		select id, transaction_id, earned_at, remaining, expirations from point_lots
+		where user_id=$1 and remaining>0
+		order by earned_at, id
+		for update
+	`, entry.UserID); err != nil {
+		return errors.Wrapf(err, "failed to get points lots of user with id %d", entry.UserID)
+	}
+
+	left := entry.Amount
+	for _, lot := range lots {
+		if left == 0 {
+			break
+		}
+
+		spent := lot.Amount
+		if spent > left {
+			spent = left
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			update point_lots set remaining=remaining-$1
+			where id=$2
+		`, spent, lot.ID); err != nil {
+			return errors.Wrapf(err, "failed to spend points lot with id %d", lot.ID)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			insert into point_lot_spends(transaction_id, user_id, lot_id, amount)
+			values ($1, $2, $3, $4)
+		`, entry.TransactionID, entry.UserID, lot.ID, spent); err != nil {
+			return errors.Wrapf(err, "failed to save spending of points lot with id %d", lot.ID)
+		}
+
+		left -= spent
+	}
+
+	return nil
+}
+
+// returnLots gives the points spent by the returned transaction back to their lots, so they keep
+// their age. The points spent before the lots were tracked come back as a new lot.
+func returnLots(ctx context.Context, tx *sqlx.Tx, returned string, entry domain.LedgerEntry) error {
+	var spends []struct {
+		LotID  int          `db:"lot_id"`
+		Amount domain.Money `db:"amount"`
+	}
+	if err := tx.SelectContext(ctx, &spends, `
+		select s.lot_id, s.amount from point_lot_spends s
+		join point_lots l on l.id=s.lot_id
+		where s.transaction_id=$1 and s.user_id=$2
+		order by l.earned_at, l.id
+	`, returned, entry.UserID); err != nil {
+		return errors.Wrapf(err, "failed to get points lots spent by transaction %s", returned)
+	}
+
+	left := entry.Amount
+	for _, spend := range spends {
+		if left == 0 {
+			break
+		}
+
+		amount := spend.Amount
+		if amount > left {
+			amount = left
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			update point_lots set remaining=remaining+$1
+			where id=$2
+		`, amount, spend.LotID); err != nil {
+			return errors.Wrapf(err, "failed to return points to lot with id %d", spend.LotID)
+		}
+
+		left -= amount
+	}
+
+	if left > 0 {
+		return addLot(ctx, tx, entry.UserID, entry.TransactionID, entry.CreatedAt, left)
+	}
+
+	return nil
+}
+
+// moveLots gives the recipient the lots of the same age as the ones spent by the sender,
+// otherwise passing the points back and forth would keep them from expiring.
+func moveLots(ctx context.Context, tx *sqlx.Tx, senderID int, entry domain.LedgerEntry) error {
+	var spends []struct {
+		EarnedAt time.Time    `db:"earned_at"`
+		Amount   domain.Money `db:"amount"`
+	}
+	if err := tx.SelectContext(ctx, &spends, `
+		select l.earned_at, s.amount from point_lot_spends s
+		join point_lots l on l.id=s.lot_id
+		where s.transaction_id=$1 and s.user_id=$2
+		order by l.earned_at, l.id
+	`, entry.TransactionID, senderID); err != nil {
+		return errors.Wrapf(err, "failed to get points lots spent by transaction %s", entry.TransactionID)
+	}
+
+	left := entry.Amount
+	for _, spend := range spends {
+		if left == 0 {
+			break
+		}
+
+		amount := spend.Amount
+		if amount > left {
+			amount = left
+		}
+
+		if err := addLot(ctx, tx, entry.UserID, entry.TransactionID, spend.EarnedAt, amount); err != nil {
+			return err
+		}
+
+		left -= amount
+	}
+
+	if left > 0 {
+		return addLot(ctx, tx, entry.UserID, entry.TransactionID, entry.CreatedAt, left)
+	}
+
+	return nil
+}
+
+// getLots returns the unspent lots of the user earned before the given moment, oldest first.
+func getLots(ctx context.Context, q sqlx.QueryerContext, userID int, earnedBefore time.Time) ([]domain.PointsLot, error) {
+	var lots []domain.PointsLot
+	if err := sqlx.SelectContext(ctx, q, &lots, `
+		select id, transaction_id, earned_at, remaining, expirations from point_lots
+		where user_id=$1 and remaining>0 and earned_at<=$2
+		order by earned_at, id
+	`, userID, earnedBefore); err != nil {
+		return lots, errors.Wrapf(err, "failed to get points lots of user with id %d", userID)
+	}
+
+	return lots, nil
+}
@@ -5,6 +5,10 @@ import (
 	"database/sql"
 	"gophermart/internal/core/apperrors"
 	"gophermart/internal/core/domain"
+	"gophermart/internal/core/ports"
+	"gophermart/internal/core/stores/ledgerstore"
+	"gophermart/internal/core/stores/referralstore"
+	"sort"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -21,9 +25,9 @@ func New(db *sqlx.DB) *OrderStore {
 
 func (o *OrderStore) AddNewOrder(ctx context.Context, userID int, orderNumber string) error {
 	if _, err := o.db.ExecContext(ctx, `
-		insert into orders(user_id, order_number, status, accrual, created_at, updated_at)
-		values ($1, $2, $3, $4, $5, $6)
-	`, userID, orderNumber, domain.OrderStatusNew, 0, time.Now(), time.Now()); err != nil {
+		insert into orders(user_id, order_number, status, accrual, created_at, updated_at, next_check_at)
+		values ($1, $2, $3, $4, $5, $6, $7)
+	`, userID, orderNumber, domain.OrderStatusNew, 0, time.Now(), time.Now(), time.Now()); err != nil {
 		return errors.Wrapf(err, "failed to insert order %s into a database, userID: %d", orderNumber, userID)
 	}
 
@@ -33,7 +37,7 @@ func (o *OrderStore) AddNewOrder(ctx context.Context, userID int, orderNumber st
 func (o *OrderStore) GetOrder(ctx context.Context, orderNumber string) (domain.Order, error) {
 	var order domain.Order
 	err := o.db.GetContext(ctx, &order, `
-		select id, user_id, order_number, status, accrual, created_at, updated_at from orders
+		select * from orders
 		where order_number=$1
 	`, orderNumber)
 
@@ -59,31 +63,65 @@ func (o *OrderStore) GetAllOrders(ctx context.Context, userID int) ([]domain.Ord
 	return orders, nil
 }
 
-func (o *OrderStore) GetAllNotFinished(ctx context.Context) ([]domain.Order, error) {
+// ClaimDueOrders takes a lease on not finished orders which should be checked in the accrual system
+// at the given moment. Orders leased by another instance are skipped, so every instance
+// works on its own set of orders. The lease is released by UpdateOrders or expires by itself
+// if the instance has crashed.
+func (o *OrderStore) ClaimDueOrders(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+	lease time.Duration,
+) ([]domain.Order, error) {
 	var orders []domain.Order
 	if err := o.db.SelectContext(ctx, &orders, `
-		select * from orders
-		where status=any($1)
-	`, []domain.OrderStatus{domain.OrderStatusNew, domain.OrderStatusProcessing}); err != nil {
-		return orders, errors.Wrapf(err, "failed to get list of not finished orders")
+		update orders
+		set locked_until=$3
+		where id in (
+			select id from orders
+			where status=any($1)
+				and not needs_review
+				and next_check_at <= $2
+				and (locked_until is null or locked_until <= $2)
+			order by next_check_at
+			limit $4
+			for update skip locked
+		)
+		returning *
+	`, []domain.OrderStatus{domain.OrderStatusNew, domain.OrderStatusProcessing}, now, now.Add(lease), limit); err != nil {
+		return orders, errors.Wrapf(err, "failed to claim due orders")
 	}
 
 	return orders, nil
 }
-func (o *OrderStore) UpdateOrders(ctx context.Context, orders []domain.Order) error {
+
+// UpdateOrders saves the result of the accrual check. The rules are applied to the processed orders
+// with the balances of their users locked, so the rules see the other orders of the user
+// saved concurrently. The points of the processed orders and the referral bonuses are posted
+// to the ledger in the same transaction.
+// The orders which are already in a final status, e.g. pushed by the accrual system
+// while being polled, are skipped. So are the orders whose lease has changed since they were read:
+// the lease has expired and another instance has claimed the order.
+func (o *OrderStore) UpdateOrders(ctx context.Context, orders []domain.Order, applyRules ports.ApplyRulesFunc) error {
 	if len(orders) == 0 {
 		return nil
 	}
 
-	tx, err := o.db.BeginTx(ctx, nil)
+	tx, err := o.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return errors.Wrap(err, "failed to start transaction")
 	}
+	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`
+	if err := lockBalances(ctx, tx, orders); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PreparexContext(ctx, `
 		update orders
-		set status=$1, accrual=$2
-		where order_number=$3
+		set status=$1, accrual=$2, base_accrual=$3, applied_rules=$4,
+			next_check_at=$5, attempts=$6, needs_review=$7, updated_at=$8, locked_until=null
+		where order_number=$9 and status<>all($10) and locked_until is not distinct from $11
 	`)
 
 	if err != nil {
@@ -92,13 +130,56 @@ func (o *OrderStore) UpdateOrders(ctx context.Context, orders []domain.Order) er
 
 	defer stmt.Close()
 
+	now := time.Now()
+	final := []domain.OrderStatus{domain.OrderStatusProcessed, domain.OrderStatusInvalid}
+	stats := func(ctx context.Context, userID int, since time.Time) (domain.AccrualStats, error) {
+		return getAccrualStats(ctx, tx, userID, since)
+	}
 	for _, order := range orders {
-		if _, err := stmt.Exec(order.Status, order.Accrual, order.OrderNumber); err != nil {
-			if err := tx.Rollback(); err != nil {
-				return errors.Wrap(err, "unable to rollback")
+		if order.Status == domain.OrderStatusProcessed && applyRules != nil {
+			order, err = applyRules(ctx, order, now, stats)
+			if err != nil {
+				return errors.Wrapf(err, "failed to apply rules to order %s", order.OrderNumber)
 			}
+		}
+
+		res, err := stmt.ExecContext(
+			ctx,
+			order.Status,
+			order.Accrual,
+			order.BaseAccrual,
+			order.AppliedRules,
+			order.NextCheckAt,
+			order.Attempts,
+			order.NeedsReview,
+			now,
+			order.OrderNumber,
+			final,
+			order.LockedUntil,
+		)
+		if err != nil {
 			return errors.Wrapf(err, "failed to exec query with order %v", order)
 		}
+
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err, "failed to get number of updated orders")
+		}
+
+		if updated == 0 || order.Status != domain.OrderStatusProcessed {
+			continue
+		}
+
+		if order.Accrual > 0 {
+			if _, err := ledgerstore.Post(ctx, tx, domain.AccrualTransaction(order, now)); err != nil {
+				return errors.Wrapf(err, "failed to post accrual of order %s", order.OrderNumber)
+			}
+		}
+
+		// The first processed order of the invited user rewards the referral
+		if err := referralstore.Reward(ctx, tx, order.UserID, order.OrderNumber, now); err != nil {
+			return errors.Wrapf(err, "failed to reward referral with order %s", order.OrderNumber)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -107,3 +188,42 @@ func (o *OrderStore) UpdateOrders(ctx context.Context, orders []domain.Order) er
 
 	return nil
 }
+
+// lockBalances locks the balances of the users whose orders are processed. The balances
+// are locked in the order of the user ids, so two concurrent batches can't deadlock.
+func lockBalances(ctx context.Context, tx *sqlx.Tx, orders []domain.Order) error {
+	var userIDs []int
+	for _, order := range orders {
+		if order.Status == domain.OrderStatusProcessed {
+			userIDs = append(userIDs, order.UserID)
+		}
+	}
+	sort.Ints(userIDs)
+
+	for i, userID := range userIDs {
+		if i > 0 && userIDs[i-1] == userID {
+			continue
+		}
+		if _, err := ledgerstore.LockBalance(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getAccrualStats returns the statistics of the processed orders of the user.
+func getAccrualStats(ctx context.Context, tx *sqlx.Tx, userID int, since time.Time) (domain.AccrualStats, error) {
+	var stats domain.AccrualStats
+	if err := tx.GetContext(ctx, &stats, `
+		select
+			count(*) as processed_orders,
+			coalesce(sum(accrual) filter (where updated_at >= $3), 0) as accrued_since
+		from orders
+		where user_id=$1 and status=$2
+	`, userID, domain.OrderStatusProcessed, since); err != nil {
+		return stats, errors.Wrapf(err, "failed to get accrual stats for user with id %d", userID)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,15 @@
+package pgerrors
+
+import (
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// uniqueViolation is the PostgreSQL error code of unique constraint violation.
+const uniqueViolation = "23505"
+
+// IsUniqueViolation reports whether the error is caused by a violated unique constraint.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,129 @@
+package promostore
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/ledgerstore"
+	"gophermart/internal/core/stores/pgerrors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type PromoStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *PromoStore {
+	return &PromoStore{db: db}
+}
+
+func (p *PromoStore) CreatePromoCode(ctx context.Context, promo domain.PromoCode) (domain.PromoCode, error) {
+	if err := p.db.GetContext(ctx, &promo.ID, `
+		insert into promo_codes(code, amount, max_redemptions, max_per_user, valid_from, valid_to, created_at)
+		values ($1, $2, $3, $4, $5, $6, $7)
+		returning id
+	`,
+		promo.Code,
+		promo.Amount,
+		promo.MaxRedemptions,
+		promo.MaxPerUser,
+		promo.ValidFrom,
+		promo.ValidTo,
+		promo.CreatedAt,
+	); err != nil {
+		if pgerrors.IsUniqueViolation(err) {
+			return promo, errors.Wrapf(apperrors.ErrPromoCodeAlreadyExists, "code '%s'", promo.Code)
+		}
+		return promo, errors.Wrapf(err, "failed to insert promo code '%s' into a database", promo.Code)
+	}
+
+	return promo, nil
+}
+
+// RedeemPromoCode credits the points of the code to the user. The code is locked while
+// the limits are checked, so concurrent redemptions can't exceed them.
+func (p *PromoStore) RedeemPromoCode(
+	ctx context.Context,
+	code string,
+	userID int,
+	now time.Time,
+) (domain.PromoRedemption, error) {
+	redemption := domain.PromoRedemption{
+		Code:       code,
+		UserID:     userID,
+		RedeemedAt: now,
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return redemption, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	var promo domain.PromoCode
+	err = tx.GetContext(ctx, &promo, `
+		select * from promo_codes
+		where code=$1
+		for update
+	`, code)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return redemption, errors.Wrapf(apperrors.ErrNoSuchPromoCode, "code '%s'", code)
+	case err != nil:
+		return redemption, errors.Wrapf(err, "failed to lock promo code '%s'", code)
+	}
+
+	if !promo.IsValid(now) {
+		return redemption, errors.Wrapf(apperrors.ErrPromoCodeNotValid, "code '%s'", code)
+	}
+
+	if promo.MaxRedemptions > 0 && promo.Redemptions >= promo.MaxRedemptions {
+		return redemption, errors.Wrapf(apperrors.ErrPromoCodeExhausted, "code '%s'", code)
+	}
+
+	if promo.MaxPerUser > 0 {
+		var used int
+		if err := tx.GetContext(ctx, &used, `
+			select count(*) from promo_redemptions
+			where promo_code_id=$1 and user_id=$2
+		`, promo.ID, userID); err != nil {
+			return redemption, errors.Wrapf(err, "failed to count redemptions of promo code '%s'", code)
+		}
+
+		if used >= promo.MaxPerUser {
+			return redemption, errors.Wrapf(apperrors.ErrPromoCodeAlreadyUsed, "code '%s'", code)
+		}
+	}
+
+	redemption.PromoCodeID = promo.ID
+	redemption.Amount = promo.Amount
+	if err := tx.GetContext(ctx, &redemption.ID, `
+		insert into promo_redemptions(promo_code_id, user_id, amount, redeemed_at)
+		values ($1, $2, $3, $4)
+		returning id
+	`, redemption.PromoCodeID, redemption.UserID, redemption.Amount, redemption.RedeemedAt); err != nil {
+		return redemption, errors.Wrapf(err, "failed to insert redemption of promo code '%s'", code)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		update promo_codes
+		set redemptions=redemptions+1
+		where id=$1
+	`, promo.ID); err != nil {
+		return redemption, errors.Wrapf(err, "failed to update redemptions of promo code '%s'", code)
+	}
+
+	if _, err := ledgerstore.Post(ctx, tx, domain.PromoTransaction(redemption)); err != nil {
+		return redemption, errors.Wrapf(err, "failed to post redemption of promo code '%s'", code)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return redemption, errors.Wrap(err, "unable to commit")
+	}
+
+	return redemption, nil
+}
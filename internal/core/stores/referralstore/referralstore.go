@@ -0,0 +1,153 @@
+package referralstore
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/ledgerstore"
+	"gophermart/internal/core/stores/pgerrors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type ReferralStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *ReferralStore {
+	return &ReferralStore{db: db}
+}
+
+// GetReferrer returns the owner of the referral code, the code must be normalized.
+func (r *ReferralStore) GetReferrer(ctx context.Context, code string) (domain.User, error) {
+	var user domain.User
+	err := r.db.GetContext(ctx, &user, `
+		select id, login, password, created_at, referral_code, token_version from users
+		where referral_code=$1
+	`, code)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return user, errors.Wrapf(apperrors.ErrNoSuchReferralCode, "code '%s'", code)
+	case err != nil:
+		return user, errors.Wrapf(err, "failed to get owner of referral code '%s'", code)
+	default:
+		return user, nil
+	}
+}
+
+func (r *ReferralStore) CountReferrals(ctx context.Context, referrerID int) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `
+		select count(*) from referrals
+		where referrer_id=$1
+	`, referrerID); err != nil {
+		return count, errors.Wrapf(err, "failed to count referrals of user with id %d", referrerID)
+	}
+
+	return count, nil
+}
+
+// AddReferral saves the referral. The referrer is locked while the referrals are counted,
+// so concurrent registrations can't exceed the limit.
+func (r *ReferralStore) AddReferral(ctx context.Context, referral domain.Referral, maxReferrals int) (domain.Referral, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return referral, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		select id from users
+		where id=$1
+		for update
+	`, referral.ReferrerID); err != nil {
+		return referral, errors.Wrapf(err, "failed to lock referrer with id %d", referral.ReferrerID)
+	}
+
+	if maxReferrals > 0 {
+		var count int
+		if err := tx.GetContext(ctx, &count, `
+			select count(*) from referrals
+			where referrer_id=$1
+		`, referral.ReferrerID); err != nil {
+			return referral, errors.Wrapf(err, "failed to count referrals of user with id %d", referral.ReferrerID)
+		}
+
+		if count >= maxReferrals {
+			return referral, errors.Wrapf(
+				apperrors.ErrReferralLimitExceeded,
+				"user with id %d has already invited %d users", referral.ReferrerID, count,
+			)
+		}
+	}
+
+	if err := tx.GetContext(ctx, &referral.ID, `
+		insert into referrals(referrer_id, referee_id, status, referrer_bonus, referee_bonus, created_at)
+		values ($1, $2, $3, $4, $5, $6)
+		returning id
+	`,
+		referral.ReferrerID,
+		referral.RefereeID,
+		referral.Status,
+		referral.ReferrerBonus,
+		referral.RefereeBonus,
+		referral.CreatedAt,
+	); err != nil {
+		if pgerrors.IsUniqueViolation(err) {
+			return referral, errors.Wrapf(apperrors.ErrAlreadyReferred, "user with id %d", referral.RefereeID)
+		}
+		return referral, errors.Wrap(err, "failed to insert referral into a database")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return referral, errors.Wrap(err, "unable to commit")
+	}
+
+	return referral, nil
+}
+
+func (r *ReferralStore) GetReferrals(ctx context.Context, referrerID int) ([]domain.Referral, error) {
+	var referrals []domain.Referral
+	if err := r.db.SelectContext(ctx, &referrals, `
+		select r.*, u.login as referee_login
+		from referrals r
+		join users u on u.id=r.referee_id
+		where r.referrer_id=$1
+		order by r.created_at
+	`, referrerID); err != nil {
+		return referrals, errors.Wrapf(err, "failed to get referrals of user with id %d", referrerID)
+	}
+
+	return referrals, nil
+}
+
+// Reward grants the bonuses of the pending referral of the user whose order has just been processed.
+// It does nothing if the user wasn't invited or the bonuses are already granted.
+func Reward(ctx context.Context, tx *sqlx.Tx, refereeID int, orderNumber string, at time.Time) error {
+	var referral domain.Referral
+	err := tx.GetContext(ctx, &referral, `
+		update referrals
+		set status=$1, order_number=$2, rewarded_at=$3
+		where referee_id=$4 and status=$5
+		returning *
+	`, domain.ReferralStatusRewarded, orderNumber, at, refereeID, domain.ReferralStatusPending)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return errors.Wrapf(err, "failed to reward referral of user with id %d", refereeID)
+	}
+
+	for _, transaction := range domain.ReferralTransactions(referral, at) {
+		if _, err := ledgerstore.Post(ctx, tx, transaction); err != nil {
+			return errors.Wrapf(err, "failed to post referral bonus of user with id %d", transaction.UserID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package tierstore
+
+import (
+	"context"
+	"gophermart/internal/core/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type TierStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *TierStore {
+	return &TierStore{db: db}
+}
+
+func (t *TierStore) GetUserTier(ctx context.Context, userID int) (domain.TierName, error) {
+	var tier domain.TierName
+	if err := t.db.GetContext(ctx, &tier, `
+		select tier from users
+		where id=$1
+	`, userID); err != nil {
+		return tier, errors.Wrapf(err, "failed to get tier of user with id %d", userID)
+	}
+
+	return tier, nil
+}
+
+// GetTierAccruals returns the current tier of every user and the sum of the base accruals
+// of the orders processed since the given moment. The base accrual is used, so the tier
+// multiplier doesn't help to keep the tier.
+func (t *TierStore) GetTierAccruals(ctx context.Context, since time.Time) ([]domain.TierAccrual, error) {
+	var accruals []domain.TierAccrual
+	if err := t.db.SelectContext(ctx, &accruals, `
+		select u.id as user_id, u.tier, coalesce(sum(o.base_accrual), 0) as accrued
+		from users u
+		left join orders o on o.user_id=u.id and o.status=$1 and o.updated_at >= $2
+		group by u.id, u.tier
+		order by u.id
+	`, domain.OrderStatusProcessed, since); err != nil {
+		return accruals, errors.Wrap(err, "failed to get tier accruals")
+	}
+
+	return accruals, nil
+}
+
+// ChangeTier moves the user to the new tier and records the change in the history.
+// It reports false if the tier of the user is not the expected one anymore.
+func (t *TierStore) ChangeTier(ctx context.Context, change domain.TierChange) (bool, error) {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		update users
+		set tier=$1
+		where id=$2 and tier=$3
+	`, change.To, change.UserID, change.From)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to change tier of user with id %d", change.UserID)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get the number of updated users")
+	}
+	if updated == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		insert into tier_changes(user_id, from_tier, to_tier, accrued, changed_at)
+		values ($1, $2, $3, $4, $5)
+	`, change.UserID, change.From, change.To, change.Accrued, change.ChangedAt); err != nil {
+		return false, errors.Wrapf(err, "failed to insert tier change of user with id %d", change.UserID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrap(err, "unable to commit")
+	}
+
+	return true, nil
+}
+
+func (t *TierStore) GetTierChanges(ctx context.Context, userID int) ([]domain.TierChange, error) {
+	var changes []domain.TierChange
+	if err := t.db.SelectContext(ctx, &changes, `
+		select * from tier_changes
+		where user_id=$1
+		order by changed_at
+	`, userID); err != nil {
+		return changes, errors.Wrapf(err, "failed to get tier changes of user with id %d", userID)
+	}
+
+	return changes, nil
+}
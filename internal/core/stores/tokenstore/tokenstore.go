@@ -0,0 +1,226 @@
+package tokenstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type TokenStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *TokenStore {
+	return &TokenStore{db: db}
+}
+
+func (t *TokenStore) CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	if _, err := t.db.ExecContext(ctx, `
+		insert into refresh_tokens(user_id, session_id, token_hash, created_at, expires_at)
+		values ($1, $2, $3, $4, $5)
+	`, token.UserID, token.SessionID, token.TokenHash, token.CreatedAt, token.ExpiresAt); err != nil {
+		return errors.Wrapf(err, "failed to insert refresh token of user with id %d", token.UserID)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken revokes the refresh token and saves the next one of the same session
+// in a single transaction. A rotated token being used again means it has leaked, so all
+// the tokens of the user are revoked then and the user has to log in again.
+// The saved token is returned with the user and the session filled in.
+func (t *TokenStore) RotateRefreshToken(
+	ctx context.Context,
+	tokenHash string,
+	next domain.RefreshToken,
+) (domain.User, domain.RefreshToken, error) {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.User{}, next, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	var token domain.RefreshToken
+	err = tx.GetContext(ctx, &token, `
+		select * from refresh_tokens
+		where token_hash=$1
+		for update
+	`, tokenHash)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return domain.User{}, next, errors.Wrap(apperrors.ErrInvalidRefreshToken, "unknown refresh token")
+	case err != nil:
+		return domain.User{}, next, errors.Wrap(err, "failed to lock refresh token")
+	}
+
+	now := next.CreatedAt
+	if token.RotatedAt != nil {
+		if err := revokeRefreshTokens(ctx, tx, token.UserID, now); err != nil {
+			return domain.User{}, next, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return domain.User{}, next, errors.Wrap(err, "unable to commit")
+		}
+
+		return domain.User{}, next, errors.Wrapf(
+			apperrors.ErrRefreshTokenReused,
+			"token %d of user with id %d", token.ID, token.UserID,
+		)
+	}
+
+	if token.RevokedAt != nil {
+		return domain.User{}, next, errors.Wrap(apperrors.ErrInvalidRefreshToken, "refresh token is revoked")
+	}
+
+	if !now.Before(token.ExpiresAt) {
+		return domain.User{}, next, errors.Wrap(apperrors.ErrInvalidRefreshToken, "refresh token is expired")
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		update refresh_tokens
+		set revoked_at=$1, rotated_at=$1
+		where id=$2
+	`, now, token.ID); err != nil {
+		return domain.User{}, next, errors.Wrapf(err, "failed to revoke refresh token %d", token.ID)
+	}
+
+	next.UserID = token.UserID
+	next.SessionID = token.SessionID
+	if next.SessionID == "" {
+		// Same as the backfill of the tokens issued before the sessions were introduced
+		next.SessionID = fmt.Sprintf("legacy-%d", token.ID)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		insert into refresh_tokens(user_id, session_id, token_hash, created_at, expires_at)
+		values ($1, $2, $3, $4, $5)
+	`, next.UserID, next.SessionID, next.TokenHash, next.CreatedAt, next.ExpiresAt); err != nil {
+		return domain.User{}, next, errors.Wrapf(err, "failed to insert refresh token of user with id %d", token.UserID)
+	}
+
+	var user domain.User
+	if err := tx.GetContext(ctx, &user, `
+		select id, login, password, created_at, referral_code, token_version from users
+		where id=$1
+	`, token.UserID); err != nil {
+		return user, next, errors.Wrapf(err, "failed to get user with id %d", token.UserID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return user, next, errors.Wrap(err, "unable to commit")
+	}
+
+	return user, next, nil
+}
+
+// RevokeSession revokes the access token and the refresh tokens of a single session.
+func (t *TokenStore) RevokeSession(ctx context.Context, session domain.Session, now time.Time) error {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		insert into revoked_tokens(jti, user_id, expires_at, revoked_at)
+		values ($1, $2, $3, $4)
+		on conflict (jti) do nothing
+	`, session.TokenID, session.UserID, session.ExpiresAt, now); err != nil {
+		return errors.Wrapf(err, "failed to revoke access token of user with id %d", session.UserID)
+	}
+
+	// The access tokens issued before the sessions were introduced have no session,
+	// they expire shortly and the refreshed ones get the session of the refresh token
+	if session.SessionID != "" {
+		if _, err := tx.ExecContext(ctx, `
+			update refresh_tokens
+			set revoked_at=$1
+			where user_id=$2 and session_id=$3 and revoked_at is null
+		`, now, session.UserID, session.SessionID); err != nil {
+			return errors.Wrapf(err, "failed to revoke refresh tokens of user with id %d", session.UserID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit")
+	}
+
+	return nil
+}
+
+// RevokeAllSessions bumps the token version of the user, so none of the access tokens
+// issued before are accepted anymore, and revokes all the refresh tokens of the user.
+func (t *TokenStore) RevokeAllSessions(ctx context.Context, userID int, now time.Time) error {
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		update users
+		set token_version=token_version+1
+		where id=$1
+	`, userID); err != nil {
+		return errors.Wrapf(err, "failed to bump token version of user with id %d", userID)
+	}
+
+	if err := revokeRefreshTokens(ctx, tx, userID, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit")
+	}
+
+	return nil
+}
+
+func (t *TokenStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var revoked bool
+	if err := t.db.GetContext(ctx, &revoked, `
+		select exists(select 1 from revoked_tokens where jti=$1)
+	`, tokenID); err != nil {
+		return false, errors.Wrapf(err, "failed to check access token %s", tokenID)
+	}
+
+	return revoked, nil
+}
+
+// DeleteExpired removes the revoked access tokens and the refresh tokens which have expired
+// by themselves, they are rejected anyway.
+func (t *TokenStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	if _, err := t.db.ExecContext(ctx, `
+		delete from revoked_tokens
+		where expires_at<=$1
+	`, now); err != nil {
+		return errors.Wrap(err, "failed to delete expired revoked tokens")
+	}
+
+	if _, err := t.db.ExecContext(ctx, `
+		delete from refresh_tokens
+		where expires_at<=$1
+	`, now); err != nil {
+		return errors.Wrap(err, "failed to delete expired refresh tokens")
+	}
+
+	return nil
+}
+
+func revokeRefreshTokens(ctx context.Context, tx *sqlx.Tx, userID int, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, `
+		update refresh_tokens
+		set revoked_at=$1
+		where user_id=$2 and revoked_at is null
+	`, now, userID); err != nil {
+		return errors.Wrapf(err, "failed to revoke refresh tokens of user with id %d", userID)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package transferstore
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/ledgerstore"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type TransferStore struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *TransferStore {
+	return &TransferStore{db: db}
+}
+
+// AddNewTransfer moves the points from the sender to the recipient with the given login.
+// Both balances are locked in the order of user IDs, so concurrent transfers between the same users
+// can't deadlock. The daily limit is checked under the lock as well.
+func (t *TransferStore) AddNewTransfer(
+	ctx context.Context,
+	senderID int,
+	recipientLogin string,
+	sum domain.Money,
+	limits domain.TransferLimits,
+) (domain.Transfer, error) {
+	transfer := domain.Transfer{
+		SenderID:  senderID,
+		Amount:    sum,
+		CreatedAt: time.Now(),
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return transfer, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &transfer.RecipientID, `
+		select id from users
+		where login=$1
+	`, recipientLogin)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return transfer, errors.Wrapf(apperrors.ErrRecipientNotFound, "login '%s'", recipientLogin)
+	case err != nil:
+		return transfer, errors.Wrapf(err, "failed to get recipient '%s'", recipientLogin)
+	case transfer.RecipientID == senderID:
+		return transfer, errors.Wrap(apperrors.ErrSelfTransfer, "recipient is the sender")
+	}
+
+	userIDs := []int{senderID, transfer.RecipientID}
+	if senderID > transfer.RecipientID {
+		userIDs[0], userIDs[1] = userIDs[1], userIDs[0]
+	}
+
+	var balance domain.UserBalance
+	for _, userID := range userIDs {
+		locked, err := ledgerstore.LockBalance(ctx, tx, userID)
+		if err != nil {
+			return transfer, err
+		}
+		if userID == senderID {
+			balance = locked
+		}
+	}
+
+	if balance.Current < sum {
+		return transfer, errors.Wrapf(apperrors.ErrNotEnoughMoney, "balance %s is less than %s", balance.Current, sum)
+	}
+
+	if limits.DailyAmount > 0 {
+		var transferred domain.Money
+		if err := tx.GetContext(ctx, &transferred, `
+			select coalesce(sum(amount), 0) from transfers
+			where sender_id=$1 and created_at>$2
+		`, senderID, transfer.CreatedAt.Add(-24*time.Hour)); err != nil {
+			return transfer, errors.Wrapf(err, "failed to get daily transfers of user with id %d", senderID)
+		}
+
+		if transferred+sum > limits.DailyAmount {
+			return transfer, errors.Wrapf(
+				apperrors.ErrTransferLimitExceeded,
+				"%s is already transferred today, the limit is %s", transferred, limits.DailyAmount,
+			)
+		}
+	}
+
+	if err := tx.GetContext(ctx, &transfer.ID, `
+		insert into transfers(sender_id, recipient_id, amount, created_at)
+		values ($1, $2, $3, $4)
+		returning id
+	`, transfer.SenderID, transfer.RecipientID, transfer.Amount, transfer.CreatedAt); err != nil {
+		return transfer, errors.Wrap(err, "failed to insert transfer into a database")
+	}
+
+	if _, err := ledgerstore.Post(ctx, tx, domain.TransferTransaction(transfer)); err != nil {
+		return transfer, errors.Wrapf(err, "failed to post transfer %d", transfer.ID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return transfer, errors.Wrap(err, "unable to commit")
+	}
+
+	return transfer, nil
+}
@@ -2,6 +2,8 @@ package userstore
 
 import (
 	"context"
+	"database/sql"
+	"gophermart/internal/core/apperrors"
 	"gophermart/internal/core/domain"
 	"time"
 
@@ -31,7 +33,7 @@ func (u *UserStore) AddNewUser(ctx context.Context, login, passwordHash string)
 func (u *UserStore) GetUser(ctx context.Context, login string) (domain.User, error) {
 	var user domain.User
 	if err := u.db.GetContext(ctx, &user, `
-		select id, login, password, created_at from users
+		select id, login, password, created_at, referral_code, token_version from users
 		where login=$1
 	`, login); err != nil {
 		return user, errors.Wrapf(err, "failed to get user %s from the database", login)
@@ -39,3 +41,112 @@ func (u *UserStore) GetUser(ctx context.Context, login string) (domain.User, err
 
 	return user, nil
 }
+
+// UpdatePassword sets the new password of the user and revokes all the sessions of the user.
+func (u *UserStore) UpdatePassword(ctx context.Context, userID int, passwordHash string, now time.Time) error {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := setPassword(ctx, tx, userID, passwordHash, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit")
+	}
+
+	return nil
+}
+
+func (u *UserStore) CreatePasswordReset(ctx context.Context, reset domain.PasswordReset) error {
+	if _, err := u.db.ExecContext(ctx, `
+		insert into password_resets(user_id, token_hash, created_at, expires_at)
+		values ($1, $2, $3, $4)
+	`, reset.UserID, reset.TokenHash, reset.CreatedAt, reset.ExpiresAt); err != nil {
+		return errors.Wrapf(err, "failed to insert password reset of user with id %d", reset.UserID)
+	}
+
+	return nil
+}
+
+// ResetPassword uses the reset token to set the new password of its user.
+func (u *UserStore) ResetPassword(
+	ctx context.Context,
+	tokenHash string,
+	passwordHash string,
+	now time.Time,
+) (domain.User, error) {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.User{}, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	var reset domain.PasswordReset
+	err = tx.GetContext(ctx, &reset, `
+		select * from password_resets
+		where token_hash=$1
+		for update
+	`, tokenHash)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return domain.User{}, errors.Wrap(apperrors.ErrInvalidResetToken, "unknown reset token")
+	case err != nil:
+		return domain.User{}, errors.Wrap(err, "failed to lock password reset")
+	case reset.UsedAt != nil:
+		return domain.User{}, errors.Wrap(apperrors.ErrInvalidResetToken, "reset token is already used")
+	case !now.Before(reset.ExpiresAt):
+		return domain.User{}, errors.Wrap(apperrors.ErrInvalidResetToken, "reset token is expired")
+	}
+
+	if err := setPassword(ctx, tx, reset.UserID, passwordHash, now); err != nil {
+		return domain.User{}, err
+	}
+
+	var user domain.User
+	if err := tx.GetContext(ctx, &user, `
+		select id, login, password, created_at, referral_code, token_version from users
+		where id=$1
+	`, reset.UserID); err != nil {
+		return user, errors.Wrapf(err, "failed to get user with id %d", reset.UserID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return user, errors.Wrap(err, "unable to commit")
+	}
+
+	return user, nil
+}
+
+// setPassword changes the password and bumps the token version, so the tokens issued with the old
+// password aren't accepted anymore. The refresh tokens and the pending resets are revoked as well.
+func setPassword(ctx context.Context, tx *sqlx.Tx, userID int, passwordHash string, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, `
+		update users
+		set password=$1, token_version=token_version+1
+		where id=$2
+	`, passwordHash, userID); err != nil {
+		return errors.Wrapf(err, "failed to update password of user with id %d", userID)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		update refresh_tokens
+		set revoked_at=$1
+		where user_id=$2 and revoked_at is null
+	`, now, userID); err != nil {
+		return errors.Wrapf(err, "failed to revoke refresh tokens of user with id %d", userID)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		update password_resets
+		set used_at=$1
+		where user_id=$2 and used_at is null
+	`, now, userID); err != nil {
+		return errors.Wrapf(err, "failed to revoke password resets of user with id %d", userID)
+	}
+
+	return nil
+}
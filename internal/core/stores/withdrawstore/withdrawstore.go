@@ -2,7 +2,11 @@ package withdrawstore
 
 import (
 	"context"
+	"database/sql"
+	"gophermart/internal/core/apperrors"
 	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/ledgerstore"
+	"gophermart/internal/core/stores/pgerrors"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -20,7 +24,7 @@ func New(db *sqlx.DB) *WithdrawStore {
 func (w *WithdrawStore) GetAllWithdrawals(ctx context.Context, userID int) ([]domain.Withdrawn, error) {
 	var withdrawals []domain.Withdrawn
 	if err := w.db.SelectContext(ctx, &withdrawals, `
-		select id, order_number, sum, processed_at, user_id from withdrawals
+		select * from withdrawals
 		where user_id=$1
 		order by processed_at
 	`, userID); err != nil {
@@ -30,13 +34,221 @@ func (w *WithdrawStore) GetAllWithdrawals(ctx context.Context, userID int) ([]do
 	return withdrawals, nil
 }
 
-func (w *WithdrawStore) AddNewWithdrawn(ctx context.Context, orderNumber string, sum int, userID int) error {
-	if _, err := w.db.ExecContext(ctx, `
-		insert into withdrawals(order_number, sum, user_id, processed_at)
-		values ($1, $2, $3, $4)
-	`, orderNumber, sum, userID, time.Now()); err != nil {
-		return errors.Wrapf(err, "failed to insert withdrawn for order '%s' into a database", orderNumber)
+// AddNewWithdrawn saves the withdrawn and posts it to the ledger. The balance of the user is locked
+// while the withdrawn is created, so concurrent withdrawals can't spend the same points twice
+// or exceed the daily limit.
+func (w *WithdrawStore) AddNewWithdrawn(
+	ctx context.Context,
+	orderNumber string,
+	sum domain.Money,
+	userID int,
+	limits domain.WithdrawalLimits,
+) error {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	balance, err := ledgerstore.LockBalance(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if balance.Current < sum {
+		return errors.Wrapf(apperrors.ErrNotEnoughMoney, "balance %s is less than %s", balance.Current, sum)
+	}
+
+	withdrawn := domain.Withdrawn{
+		OrderNumber: orderNumber,
+		Sum:         sum,
+		ProcessedAt: time.Now(),
+		UserID:      userID,
+		Status:      domain.WithdrawnStatusPending,
+	}
+
+	if err := CheckDailyLimit(ctx, tx, userID, sum, limits, withdrawn.ProcessedAt); err != nil {
+		return err
+	}
+
+	if err := Insert(ctx, tx, &withdrawn); err != nil {
+		return err
+	}
+
+	if _, err := ledgerstore.Post(ctx, tx, domain.WithdrawalTransaction(withdrawn)); err != nil {
+		return errors.Wrapf(err, "failed to post withdrawn for order '%s'", orderNumber)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit")
+	}
+
+	return nil
+}
+
+// CheckDailyLimit tells whether the user may spend the sum on top of the withdrawals of the last
+// 24 hours. The points reserved by the active holds are counted as well, since they are withdrawn
+// when the hold is captured. The balance of the user must be locked by the given transaction.
+func CheckDailyLimit(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID int,
+	sum domain.Money,
+	limits domain.WithdrawalLimits,
+	now time.Time,
+) error {
+	if limits.DailyAmount <= 0 {
+		return nil
+	}
+
+	var withdrawnToday domain.Money
+	if err := tx.GetContext(ctx, &withdrawnToday, `
+		select
+			(select coalesce(sum(sum), 0) from withdrawals
+			 where user_id=$1 and processed_at>$2 and status<>$3)
+			+ (select coalesce(sum(amount), 0) from point_holds
+			 where user_id=$1 and status=$4)
+	`, userID, now.Add(-24*time.Hour), domain.WithdrawnStatusReversed, domain.HoldStatusActive); err != nil {
+		return errors.Wrapf(err, "failed to get daily withdrawals of user with id %d", userID)
+	}
+
+	if withdrawnToday+sum > limits.DailyAmount {
+		return errors.Wrapf(
+			apperrors.ErrWithdrawalLimitExceeded,
+			"%s is already withdrawn today, the limit is %s", withdrawnToday, limits.DailyAmount,
+		)
+	}
+
+	return nil
+}
+
+// Insert saves the withdrawn within the given database transaction and sets its ID.
+func Insert(ctx context.Context, tx *sqlx.Tx, withdrawn *domain.Withdrawn) error {
+	if err := tx.GetContext(ctx, &withdrawn.ID, `
+		insert into withdrawals(order_number, sum, user_id, processed_at, status)
+		values ($1, $2, $3, $4, $5)
+		returning id
+	`, withdrawn.OrderNumber, withdrawn.Sum, withdrawn.UserID, withdrawn.ProcessedAt, withdrawn.Status); err != nil {
+		if pgerrors.IsUniqueViolation(err) {
+			return errors.Wrapf(apperrors.ErrWithdrawalAlreadyExists, "order '%s'", withdrawn.OrderNumber)
+		}
+		return errors.Wrapf(err, "failed to insert withdrawn for order '%s' into a database", withdrawn.OrderNumber)
+	}
+
+	return nil
+}
+
+// CompleteWithdrawn marks the pending withdrawn as completed. Completing it twice does nothing.
+func (w *WithdrawStore) CompleteWithdrawn(ctx context.Context, orderNumber string) error {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	withdrawn, err := lockWithdrawn(ctx, tx, orderNumber)
+	if err != nil {
+		return err
+	}
+
+	switch withdrawn.Status {
+	case domain.WithdrawnStatusCompleted:
+		return nil
+	case domain.WithdrawnStatusReversed:
+		return errors.Wrapf(apperrors.ErrWithdrawalReversed, "order '%s'", orderNumber)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		update withdrawals
+		set status=$1
+		where id=$2
+	`, domain.WithdrawnStatusCompleted, withdrawn.ID); err != nil {
+		return errors.Wrapf(err, "failed to complete withdrawn for order '%s'", orderNumber)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit")
 	}
 
 	return nil
 }
+
+// ReverseWithdrawn marks the withdrawn as reversed and gives its points back to the user
+// within the same database transaction.
+func (w *WithdrawStore) ReverseWithdrawn(ctx context.Context, orderNumber string, reason string) error {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	withdrawn, err := lockWithdrawn(ctx, tx, orderNumber)
+	if err != nil {
+		return err
+	}
+
+	if withdrawn.Status == domain.WithdrawnStatusReversed {
+		return errors.Wrapf(apperrors.ErrWithdrawalReversed, "order '%s'", orderNumber)
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `
+		update withdrawals
+		set status=$1, reversed_at=$2, reversal_reason=$3
+		where id=$4
+	`, domain.WithdrawnStatusReversed, now, reason, withdrawn.ID); err != nil {
+		return errors.Wrapf(err, "failed to reverse withdrawn for order '%s'", orderNumber)
+	}
+
+	spentBy, err := getSpendingTransaction(ctx, tx, withdrawn)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ledgerstore.Post(ctx, tx, domain.ReversalTransaction(withdrawn, spentBy, now)); err != nil {
+		return errors.Wrapf(err, "failed to post reversal of withdrawn for order '%s'", orderNumber)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit")
+	}
+
+	return nil
+}
+
+// getSpendingTransaction returns the transaction which has taken the points of the withdrawn
+// from the points account: the hold if the withdrawn is a captured hold, the withdrawal otherwise.
+func getSpendingTransaction(ctx context.Context, tx *sqlx.Tx, withdrawn domain.Withdrawn) (string, error) {
+	var hold domain.Hold
+	err := tx.GetContext(ctx, &hold.ID, `
+		select id from point_holds
+		where order_number=$1 and user_id=$2 and status=$3
+	`, withdrawn.OrderNumber, withdrawn.UserID, domain.HoldStatusCaptured)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return domain.WithdrawalTransaction(withdrawn).ID, nil
+	case err != nil:
+		return "", errors.Wrapf(err, "failed to get captured hold for order '%s'", withdrawn.OrderNumber)
+	default:
+		return domain.HoldTransaction(hold).ID, nil
+	}
+}
+
+func lockWithdrawn(ctx context.Context, tx *sqlx.Tx, orderNumber string) (domain.Withdrawn, error) {
+	var withdrawn domain.Withdrawn
+	err := tx.GetContext(ctx, &withdrawn, `
+		select * from withdrawals
+		where order_number=$1
+		for update
+	`, orderNumber)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return withdrawn, errors.Wrapf(apperrors.ErrNoSuchWithdrawal, "order '%s'", orderNumber)
+	case err != nil:
+		return withdrawn, errors.Wrapf(err, "failed to lock withdrawn for order '%s'", orderNumber)
+	default:
+		return withdrawn, nil
+	}
+}
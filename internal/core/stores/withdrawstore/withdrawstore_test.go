@@ -0,0 +1,119 @@
+package withdrawstore
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/core/apperrors"
+	"gophermart/internal/core/domain"
+	"gophermart/internal/core/stores/ledgerstore"
+	"gophermart/internal/core/stores/orderstore"
+	"gophermart/internal/core/stores/storetest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests need a real database, see storetest.
+func TestWithdrawStore_ConcurrentWithdrawals(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	const (
+		accrual     = 10000
+		withdrawals = 300
+		sum         = 100
+	)
+
+	userID := newTestUser(t, dbx, accrual)
+	withdrawStore := New(dbx)
+
+	var wg sync.WaitGroup
+	var mx sync.Mutex
+	var succeeded, rejected int
+	for i := 0; i < withdrawals; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := withdrawStore.AddNewWithdrawn(ctx, fmt.Sprintf("%d-%d", userID, i), sum, userID, domain.WithdrawalLimits{})
+
+			mx.Lock()
+			defer mx.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, apperrors.ErrNotEnoughMoney):
+				rejected++
+			default:
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, accrual/sum, succeeded)
+	assert.Equal(t, withdrawals-accrual/sum, rejected)
+
+	balance, err := ledgerstore.New(dbx).GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserBalance{Current: 0, Withdrawn: accrual}, balance)
+}
+
+func TestWithdrawStore_ReverseWithdrawn(t *testing.T) {
+	dbx := storetest.NewDB(t)
+	ctx := context.Background()
+
+	userID := newTestUser(t, dbx, 10000)
+	withdrawStore := New(dbx)
+	orderNumber := fmt.Sprintf("%d-reversed", userID)
+
+	require.NoError(t, withdrawStore.AddNewWithdrawn(ctx, orderNumber, 4000, userID, domain.WithdrawalLimits{}))
+	require.NoError(t, withdrawStore.ReverseWithdrawn(ctx, orderNumber, "order is cancelled"))
+
+	err := withdrawStore.ReverseWithdrawn(ctx, orderNumber, "order is cancelled")
+	assert.ErrorIs(t, err, apperrors.ErrWithdrawalReversed)
+
+	err = withdrawStore.CompleteWithdrawn(ctx, orderNumber)
+	assert.ErrorIs(t, err, apperrors.ErrWithdrawalReversed)
+
+	withdrawals, err := withdrawStore.GetAllWithdrawals(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, withdrawals, 1)
+	assert.Equal(t, domain.WithdrawnStatusReversed, withdrawals[0].Status)
+	assert.Equal(t, "order is cancelled", withdrawals[0].ReversalReason)
+
+	balance, err := ledgerstore.New(dbx).GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserBalance{Current: 10000, Withdrawn: 0}, balance)
+
+	// The points return to the lot of the accrual, so they keep their age
+	lots, err := ledgerstore.New(dbx).GetLots(ctx, userID, time.Now())
+	require.NoError(t, err)
+	require.Len(t, lots, 1)
+	assert.Equal(t, domain.Money(10000), lots[0].Amount)
+}
+
+// -- Test helpers --
+
+// newTestUser creates a user with a processed order which brings the given accrual.
+func newTestUser(t *testing.T, dbx *sqlx.DB, accrual domain.Money) int {
+	ctx := context.Background()
+	userID := storetest.NewUser(t, dbx)
+
+	orderStore := orderstore.New(dbx)
+	orderNumber := fmt.Sprintf("%d", time.Now().UnixNano())
+	require.NoError(t, orderStore.AddNewOrder(ctx, userID, orderNumber))
+
+	order, err := orderStore.GetOrder(ctx, orderNumber)
+	require.NoError(t, err)
+	order.Status = domain.OrderStatusProcessed
+	order.Accrual = accrual
+	require.NoError(t, orderStore.UpdateOrders(ctx, []domain.Order{order}, nil))
+
+	return userID
+}
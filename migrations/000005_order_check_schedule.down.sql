@@ -0,0 +1,6 @@
+drop index orders_next_check_at_idx;
+
+alter table orders
+drop column next_check_at,
+drop column attempts,
+drop column needs_review;
@@ -0,0 +1,7 @@
+alter table orders
+add column next_check_at timestamp not null default now(),
+add column attempts int not null default 0,
+add column needs_review boolean not null default false;
+
+create index orders_next_check_at_idx on orders(next_check_at)
+where status in ('NEW', 'PROCESSING') and not needs_review;
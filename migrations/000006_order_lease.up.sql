@@ -0,0 +1,2 @@
+alter table orders
+add column locked_until timestamp;
@@ -0,0 +1,7 @@
+drop table balances;
+
+drop trigger ledger_entries_immutable on ledger_entries;
+
+drop function forbid_ledger_entries_change;
+
+drop table ledger_entries;
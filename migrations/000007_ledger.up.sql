@@ -0,0 +1,70 @@
+create table ledger_entries (
+    id serial primary key,
+    transaction_id varchar not null,
+    user_id int not null,
+    account varchar not null,
+    direction varchar not null,
+    amount bigint not null,
+    reason varchar not null,
+    reference varchar not null default '',
+    created_at timestamp not null,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id),
+
+    constraint unique_transaction_account
+        unique (transaction_id, account),
+
+    constraint positive_amount
+        check (amount > 0),
+
+    constraint known_direction
+        check (direction in ('DEBIT', 'CREDIT'))
+);
+
+create index ledger_entries_user_id_idx on ledger_entries(user_id, account, created_at);
+
+create function forbid_ledger_entries_change() returns trigger as $$
+begin
+    raise exception 'ledger entries are immutable';
+end;
+$$ language plpgsql;
+
+create trigger ledger_entries_immutable
+before update or delete on ledger_entries
+for each row execute function forbid_ledger_entries_change();
+
+create table balances (
+    user_id int primary key,
+    current bigint not null default 0,
+    withdrawn bigint not null default 0,
+    updated_at timestamp not null,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id)
+);
+
+-- Post the history which happened before the ledger
+insert into ledger_entries(transaction_id, user_id, account, direction, amount, reason, reference, created_at)
+select 'accrual:' || order_number, user_id, account, direction, accrual::bigint, 'ACCRUAL', order_number, updated_at
+from orders
+cross join (values ('ACCRUALS', 'DEBIT'), ('POINTS', 'CREDIT')) as legs(account, direction)
+where status = 'PROCESSED' and accrual > 0;
+
+insert into ledger_entries(transaction_id, user_id, account, direction, amount, reason, reference, created_at)
+select 'withdrawal:' || id, user_id, account, direction, sum, 'WITHDRAWAL', order_number, processed_at
+from withdrawals
+cross join (values ('POINTS', 'DEBIT'), ('WITHDRAWALS', 'CREDIT')) as legs(account, direction)
+where sum > 0;
+
+insert into balances(user_id, current, withdrawn, updated_at)
+select
+    user_id,
+    coalesce(sum(case when account = 'POINTS' and direction = 'CREDIT' then amount end), 0)
+        - coalesce(sum(case when account = 'POINTS' and direction = 'DEBIT' then amount end), 0),
+    coalesce(sum(case when account = 'WITHDRAWALS' and direction = 'CREDIT' then amount end), 0),
+    now()
+from ledger_entries
+group by user_id;
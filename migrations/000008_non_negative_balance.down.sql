@@ -0,0 +1,4 @@
+alter table balances
+drop constraint non_negative_current;
+
+drop table balance_overdrafts;
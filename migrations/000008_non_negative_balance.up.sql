@@ -0,0 +1,28 @@
+-- Concurrent withdrawals could overdraw the balance before. The overdrafts are kept for the operators
+-- and written off with the ledger entries, so the ledger and the balances stay in sync.
+create table balance_overdrafts (
+    user_id int primary key,
+    amount bigint not null,
+    found_at timestamp not null,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id)
+);
+
+insert into balance_overdrafts(user_id, amount, found_at)
+select user_id, -current, now() from balances
+where current < 0;
+
+insert into ledger_entries(transaction_id, user_id, account, direction, amount, reason, reference, created_at)
+select 'overdraft:' || user_id, user_id, account, direction, amount, 'OVERDRAFT_WRITE_OFF', '', found_at
+from balance_overdrafts
+cross join (values ('BONUSES', 'DEBIT'), ('POINTS', 'CREDIT')) as legs(account, direction);
+
+update balances
+set current = 0,
+    updated_at = now()
+where current < 0;
+
+alter table balances
+add constraint non_negative_current check (current >= 0);
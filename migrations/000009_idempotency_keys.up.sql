@@ -0,0 +1,18 @@
+create table idempotency_keys (
+    user_id int not null,
+    key varchar not null,
+    request_hash varchar not null,
+    status_code int not null default 0,
+    content_type varchar not null default '',
+    response_body bytea not null default '',
+    created_at timestamp not null,
+    expires_at timestamp not null,
+
+    primary key (user_id, key),
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id)
+);
+
+create index idempotency_keys_expires_at_idx on idempotency_keys(expires_at);
@@ -0,0 +1,2 @@
+alter table withdrawals
+drop constraint unique_withdrawal_order_number;
@@ -0,0 +1,33 @@
+-- Withdrawals for the same order were possible before, only the first one of them is kept.
+-- The points of the others are given back to the users with the reversing ledger entries.
+create temporary table duplicate_withdrawals as
+select w.id, w.user_id, w.order_number, w.sum from withdrawals w
+where exists (
+    select 1 from withdrawals f
+    where f.order_number = w.order_number and f.id < w.id
+);
+
+insert into ledger_entries(transaction_id, user_id, account, direction, amount, reason, reference, created_at)
+select 'reversal:' || id, user_id, account, direction, sum, 'WITHDRAWAL_REVERSAL', order_number, now()
+from duplicate_withdrawals
+cross join (values ('WITHDRAWALS', 'DEBIT'), ('POINTS', 'CREDIT')) as legs(account, direction)
+where sum > 0;
+
+update balances b
+set current = b.current + d.sum,
+    withdrawn = b.withdrawn - d.sum,
+    updated_at = now()
+from (
+    select user_id, sum(sum) as sum from duplicate_withdrawals
+    where sum > 0
+    group by user_id
+) d
+where b.user_id = d.user_id;
+
+delete from withdrawals
+where id in (select id from duplicate_withdrawals);
+
+drop table duplicate_withdrawals;
+
+alter table withdrawals
+add constraint unique_withdrawal_order_number unique (order_number);
@@ -0,0 +1,4 @@
+alter table orders
+alter column accrual drop not null,
+alter column accrual drop default,
+alter column accrual type numeric;
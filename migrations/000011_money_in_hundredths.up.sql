@@ -0,0 +1,8 @@
+update orders
+set accrual=0
+where accrual is null;
+
+alter table orders
+alter column accrual type bigint using round(accrual)::bigint,
+alter column accrual set default 0,
+alter column accrual set not null;
@@ -0,0 +1,5 @@
+alter table withdrawals
+drop constraint known_withdrawal_status,
+drop column reversal_reason,
+drop column reversed_at,
+drop column status;
@@ -0,0 +1,12 @@
+alter table withdrawals
+add column status varchar not null default 'COMPLETED',
+add column reversed_at timestamp,
+add column reversal_reason varchar not null default '';
+
+alter table withdrawals
+add constraint known_withdrawal_status
+check (status in ('PENDING', 'COMPLETED', 'REVERSED'));
+
+-- New withdrawals wait for the confirmation of the shop
+alter table withdrawals
+alter column status set default 'PENDING';
@@ -0,0 +1,5 @@
+alter table balances
+drop constraint non_negative_held,
+drop column held;
+
+drop table point_holds;
@@ -0,0 +1,29 @@
+create table point_holds (
+    id serial primary key,
+    user_id int not null,
+    order_number varchar not null,
+    amount bigint not null,
+    status varchar not null,
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    finished_at timestamp,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id),
+
+    constraint unique_hold_order_number
+        unique (order_number),
+
+    constraint positive_hold_amount
+        check (amount > 0),
+
+    constraint known_hold_status
+        check (status in ('ACTIVE', 'CAPTURED', 'RELEASED', 'EXPIRED'))
+);
+
+create index point_holds_expires_at_idx on point_holds(expires_at) where status = 'ACTIVE';
+
+alter table balances
+add column held bigint not null default 0,
+add constraint non_negative_held check (held >= 0);
@@ -0,0 +1,5 @@
+alter table ledger_entries
+drop constraint unique_transaction_user_account,
+add constraint unique_transaction_account unique (transaction_id, account);
+
+drop table transfers;
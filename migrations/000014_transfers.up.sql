@@ -0,0 +1,28 @@
+create table transfers (
+    id serial primary key,
+    sender_id int not null,
+    recipient_id int not null,
+    amount bigint not null,
+    created_at timestamp not null,
+
+    constraint fk_sender_id
+        foreign key(sender_id)
+        references users(id),
+
+    constraint fk_recipient_id
+        foreign key(recipient_id)
+        references users(id),
+
+    constraint positive_transfer_amount
+        check (amount > 0),
+
+    constraint no_self_transfer
+        check (sender_id <> recipient_id)
+);
+
+create index transfers_sender_id_idx on transfers(sender_id, created_at);
+
+-- Both legs of a transfer are posted to the points accounts, but of different users
+alter table ledger_entries
+drop constraint unique_transaction_account,
+add constraint unique_transaction_user_account unique (transaction_id, user_id, account);
@@ -0,0 +1,3 @@
+alter table orders
+drop column applied_rules,
+drop column base_accrual;
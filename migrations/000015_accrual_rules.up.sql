@@ -0,0 +1,6 @@
+alter table orders
+add column base_accrual bigint not null default 0,
+add column applied_rules jsonb not null default '[]';
+
+update orders
+set base_accrual=accrual;
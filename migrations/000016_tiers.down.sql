@@ -0,0 +1,7 @@
+drop index withdrawals_user_id_processed_at_idx;
+drop index orders_processed_updated_at_idx;
+
+drop table tier_changes;
+
+alter table users
+drop column tier;
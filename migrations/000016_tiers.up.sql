@@ -0,0 +1,23 @@
+alter table users
+add column tier varchar not null default 'BRONZE';
+
+create table tier_changes (
+    id serial primary key,
+    user_id int not null,
+    from_tier varchar not null,
+    to_tier varchar not null,
+    accrued bigint not null,
+    changed_at timestamp not null,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id)
+);
+
+create index tier_changes_user_id_idx on tier_changes(user_id, changed_at);
+
+-- The tiers are calculated from the accruals of the processed orders within a rolling window
+create index orders_processed_updated_at_idx on orders(updated_at) where status = 'PROCESSED';
+
+-- Daily withdrawal limits
+create index withdrawals_user_id_processed_at_idx on withdrawals(user_id, processed_at);
@@ -0,0 +1,4 @@
+drop table referrals;
+
+alter table users
+drop column referral_code;
@@ -0,0 +1,42 @@
+alter table users
+add column referral_code varchar;
+
+update users
+set referral_code=upper(substr(md5(random()::text || id::text), 1, 10));
+
+alter table users
+alter column referral_code set not null,
+alter column referral_code set default upper(substr(md5(random()::text), 1, 10)),
+add constraint unique_referral_code unique (referral_code);
+
+create table referrals (
+    id serial primary key,
+    referrer_id int not null,
+    referee_id int not null,
+    status varchar not null default 'PENDING',
+    referrer_bonus bigint not null,
+    referee_bonus bigint not null,
+    order_number varchar not null default '',
+    created_at timestamp not null,
+    rewarded_at timestamp,
+
+    constraint fk_referrer_id
+        foreign key(referrer_id)
+        references users(id),
+
+    constraint fk_referee_id
+        foreign key(referee_id)
+        references users(id),
+
+    -- A user can be invited only once
+    constraint unique_referee_id
+        unique (referee_id),
+
+    constraint no_self_referral
+        check (referrer_id <> referee_id),
+
+    constraint known_referral_status
+        check (status in ('PENDING', 'REWARDED'))
+);
+
+create index referrals_referrer_id_idx on referrals(referrer_id, created_at);
@@ -0,0 +1,2 @@
+drop table promo_redemptions;
+drop table promo_codes;
@@ -0,0 +1,42 @@
+create table promo_codes (
+    id serial primary key,
+    code varchar not null,
+    amount bigint not null,
+    max_redemptions int not null default 0,
+    max_per_user int not null default 1,
+    redemptions int not null default 0,
+    valid_from timestamp,
+    valid_to timestamp,
+    created_at timestamp not null,
+
+    constraint unique_promo_code
+        unique (code),
+
+    constraint positive_promo_amount
+        check (amount > 0),
+
+    -- 0 means the code can be redeemed any number of times
+    constraint non_negative_promo_limits
+        check (max_redemptions >= 0 and max_per_user >= 0),
+
+    constraint promo_redemptions_within_limit
+        check (max_redemptions = 0 or redemptions <= max_redemptions)
+);
+
+create table promo_redemptions (
+    id serial primary key,
+    promo_code_id int not null,
+    user_id int not null,
+    amount bigint not null,
+    redeemed_at timestamp not null,
+
+    constraint fk_promo_code_id
+        foreign key(promo_code_id)
+        references promo_codes(id),
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id)
+);
+
+create index promo_redemptions_user_id_idx on promo_redemptions(promo_code_id, user_id);
@@ -0,0 +1 @@
+drop table refresh_tokens;
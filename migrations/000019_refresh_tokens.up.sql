@@ -0,0 +1,18 @@
+create table refresh_tokens (
+    id serial primary key,
+    user_id int not null,
+    -- Only the hash of the token is stored, so a leaked table doesn't give access to the accounts
+    token_hash varchar not null,
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    revoked_at timestamp,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id),
+
+    constraint unique_token_hash
+        unique (token_hash)
+);
+
+create index refresh_tokens_user_id_idx on refresh_tokens(user_id) where revoked_at is null;
@@ -0,0 +1,5 @@
+drop table revoked_tokens;
+drop index refresh_tokens_session_id_idx;
+alter table refresh_tokens drop column rotated_at;
+alter table refresh_tokens drop column session_id;
+alter table users drop column token_version;
@@ -0,0 +1,25 @@
+-- Bumping the version invalidates all the access tokens issued to the user before
+alter table users add column token_version int not null default 0;
+
+-- The refresh tokens of one login share the session id through the rotations
+alter table refresh_tokens add column session_id varchar not null default '';
+-- Every token issued before is a session of its own, so the logout can revoke it
+update refresh_tokens set session_id = 'legacy-' || id;
+-- A rotated token is revoked too, but only the rotated one being reused means it has leaked
+alter table refresh_tokens add column rotated_at timestamp;
+
+create index refresh_tokens_session_id_idx on refresh_tokens(user_id, session_id) where revoked_at is null;
+
+-- The access tokens revoked at the logout, kept until they expire by themselves
+create table revoked_tokens (
+    jti varchar primary key,
+    user_id int not null,
+    expires_at timestamp not null,
+    revoked_at timestamp not null,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id)
+);
+
+create index revoked_tokens_expires_at_idx on revoked_tokens(expires_at);
@@ -0,0 +1,18 @@
+create table password_resets (
+    id serial primary key,
+    user_id int not null,
+    -- Only the hash of the token is stored, the token itself is sent to the user
+    token_hash varchar not null,
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id),
+
+    constraint unique_reset_token_hash
+        unique (token_hash)
+);
+
+create index password_resets_user_id_idx on password_resets(user_id) where used_at is null;
@@ -0,0 +1,2 @@
+drop table point_lot_spends;
+drop table point_lots;
@@ -0,0 +1,63 @@
+create table point_lots (
+    id serial primary key,
+    user_id int not null,
+    -- The posting which has brought the points
+    transaction_id varchar not null,
+    earned_at timestamp not null,
+    amount bigint not null,
+    remaining bigint not null,
+    -- The released points return to the lot, so it may expire more than once
+    expirations int not null default 0,
+
+    constraint fk_user_id
+        foreign key(user_id)
+        references users(id),
+
+    constraint positive_lot_amount
+        check (amount > 0),
+
+    constraint remaining_lot_amount
+        check (remaining between 0 and amount)
+);
+
+create index point_lots_user_id_idx on point_lots(user_id, earned_at) where remaining > 0;
+create index point_lots_earned_at_idx on point_lots(earned_at) where remaining > 0;
+
+-- Every debit of the points account remembers the lots it has consumed
+create table point_lot_spends (
+    transaction_id varchar not null,
+    user_id int not null,
+    lot_id int not null,
+    amount bigint not null,
+
+    primary key (transaction_id, user_id, lot_id),
+
+    constraint fk_lot_id
+        foreign key(lot_id)
+        references point_lots(id),
+
+    constraint positive_spend_amount
+        check (amount > 0)
+);
+
+-- Split the points earned before into the lots, the points are spent oldest first
+-- and the released points are not spent
+insert into point_lots(user_id, transaction_id, earned_at, amount, remaining)
+select user_id, transaction_id, created_at, amount, greatest(0, least(amount, earned - spent))
+from (
+    select
+        c.user_id,
+        c.transaction_id,
+        c.created_at,
+        c.amount,
+        sum(c.amount) over (partition by c.user_id order by c.created_at, c.id) as earned,
+        coalesce(s.spent, 0) as spent
+    from ledger_entries c
+    left join (
+        select user_id, sum(case when direction = 'DEBIT' then amount else -amount end) as spent
+        from ledger_entries
+        where account = 'POINTS' and (direction = 'DEBIT' or reason = 'HOLD_RELEASE')
+        group by user_id
+    ) s on s.user_id = c.user_id
+    where c.account = 'POINTS' and c.direction = 'CREDIT' and c.reason <> 'HOLD_RELEASE'
+) lots;
@@ -0,0 +1,5 @@
+alter table users
+drop constraint normalized_referral_code;
+
+alter table promo_codes
+drop constraint normalized_promo_code;
@@ -0,0 +1,14 @@
+-- The codes are matched after domain.NormalizeCode, so a code stored in any other form can't be found.
+update promo_codes
+set code=upper(trim(code))
+where code<>upper(trim(code));
+
+alter table promo_codes
+add constraint normalized_promo_code check (code=upper(trim(code)));
+
+update users
+set referral_code=upper(trim(referral_code))
+where referral_code<>upper(trim(referral_code));
+
+alter table users
+add constraint normalized_referral_code check (referral_code=upper(trim(referral_code)));
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: gophermart/internal/core/ports (interfaces: UserService,OrderService)
+// Source: gophermart/internal/core/ports (interfaces: UserService,OrderService,HoldService,TransferService,TierService,ReferralService,PromoService,IdempotencyService,AccrualService,Notifier,AccrualRules,AccrualProcessor)
 
 // Package ports is a generated GoMock package.
 package ports
@@ -7,7 +7,9 @@ package ports
 import (
 	context "context"
 	domain "gophermart/internal/core/domain"
+	ports "gophermart/internal/core/ports"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -50,11 +52,26 @@ func (mr *MockUserServiceMockRecorder) AuthenticateUser(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateUser", reflect.TypeOf((*MockUserService)(nil).AuthenticateUser), arg0, arg1)
 }
 
+// ChangePassword mocks base method.
+func (m *MockUserService) ChangePassword(arg0 context.Context, arg1 *domain.User, arg2, arg3 string) (domain.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUserServiceMockRecorder) ChangePassword(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUserService)(nil).ChangePassword), arg0, arg1, arg2, arg3)
+}
+
 // LoginUser mocks base method.
-func (m *MockUserService) LoginUser(arg0 context.Context, arg1, arg2 string) (string, error) {
+func (m *MockUserService) LoginUser(arg0 context.Context, arg1, arg2 string) (domain.Tokens, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "LoginUser", arg0, arg1, arg2)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(domain.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -65,19 +82,90 @@ func (mr *MockUserServiceMockRecorder) LoginUser(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginUser", reflect.TypeOf((*MockUserService)(nil).LoginUser), arg0, arg1, arg2)
 }
 
+// Logout mocks base method.
+func (m *MockUserService) Logout(arg0 context.Context, arg1 *domain.User, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockUserServiceMockRecorder) Logout(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockUserService)(nil).Logout), arg0, arg1, arg2)
+}
+
+// LogoutAll mocks base method.
+func (m *MockUserService) LogoutAll(arg0 context.Context, arg1 *domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutAll", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutAll indicates an expected call of LogoutAll.
+func (mr *MockUserServiceMockRecorder) LogoutAll(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutAll", reflect.TypeOf((*MockUserService)(nil).LogoutAll), arg0, arg1)
+}
+
+// RefreshTokens mocks base method.
+func (m *MockUserService) RefreshTokens(arg0 context.Context, arg1 string) (domain.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshTokens", arg0, arg1)
+	ret0, _ := ret[0].(domain.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshTokens indicates an expected call of RefreshTokens.
+func (mr *MockUserServiceMockRecorder) RefreshTokens(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokens", reflect.TypeOf((*MockUserService)(nil).RefreshTokens), arg0, arg1)
+}
+
 // RegisterUser mocks base method.
-func (m *MockUserService) RegisterUser(arg0 context.Context, arg1, arg2 string) (string, error) {
+func (m *MockUserService) RegisterUser(arg0 context.Context, arg1, arg2, arg3 string) (domain.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RegisterUser", arg0, arg1, arg2)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "RegisterUser", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RegisterUser indicates an expected call of RegisterUser.
-func (mr *MockUserServiceMockRecorder) RegisterUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) RegisterUser(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockUserService)(nil).RegisterUser), arg0, arg1, arg2, arg3)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockUserService) RequestPasswordReset(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestPasswordReset", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockUserServiceMockRecorder) RequestPasswordReset(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockUserService)(nil).RequestPasswordReset), arg0, arg1)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUserService) ResetPassword(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserServiceMockRecorder) ResetPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockUserService)(nil).RegisterUser), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUserService)(nil).ResetPassword), arg0, arg1, arg2)
 }
 
 // MockOrderService is a mock of OrderService interface.
@@ -117,6 +205,20 @@ func (mr *MockOrderServiceMockRecorder) AddOrder(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOrder", reflect.TypeOf((*MockOrderService)(nil).AddOrder), arg0, arg1, arg2)
 }
 
+// CompleteWithdrawal mocks base method.
+func (m *MockOrderService) CompleteWithdrawal(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteWithdrawal", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteWithdrawal indicates an expected call of CompleteWithdrawal.
+func (mr *MockOrderServiceMockRecorder) CompleteWithdrawal(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteWithdrawal", reflect.TypeOf((*MockOrderService)(nil).CompleteWithdrawal), arg0, arg1)
+}
+
 // GetAllOrders mocks base method.
 func (m *MockOrderService) GetAllOrders(arg0 context.Context, arg1 *domain.User) ([]domain.Order, error) {
 	m.ctrl.T.Helper()
@@ -147,6 +249,36 @@ func (mr *MockOrderServiceMockRecorder) GetAllWithdrawals(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllWithdrawals", reflect.TypeOf((*MockOrderService)(nil).GetAllWithdrawals), arg0, arg1)
 }
 
+// GetBalanceHistory mocks base method.
+func (m *MockOrderService) GetBalanceHistory(arg0 context.Context, arg1 *domain.User) ([]domain.LedgerEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceHistory", arg0, arg1)
+	ret0, _ := ret[0].([]domain.LedgerEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceHistory indicates an expected call of GetBalanceHistory.
+func (mr *MockOrderServiceMockRecorder) GetBalanceHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceHistory", reflect.TypeOf((*MockOrderService)(nil).GetBalanceHistory), arg0, arg1)
+}
+
+// GetOrder mocks base method.
+func (m *MockOrderService) GetOrder(arg0 context.Context, arg1 string) (domain.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrder", arg0, arg1)
+	ret0, _ := ret[0].(domain.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrder indicates an expected call of GetOrder.
+func (mr *MockOrderServiceMockRecorder) GetOrder(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockOrderService)(nil).GetOrder), arg0, arg1)
+}
+
 // GetUserBalance mocks base method.
 func (m *MockOrderService) GetUserBalance(arg0 context.Context, arg1 *domain.User) (domain.UserBalance, error) {
 	m.ctrl.T.Helper()
@@ -162,8 +294,22 @@ func (mr *MockOrderServiceMockRecorder) GetUserBalance(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserBalance", reflect.TypeOf((*MockOrderService)(nil).GetUserBalance), arg0, arg1)
 }
 
+// ReverseWithdrawal mocks base method.
+func (m *MockOrderService) ReverseWithdrawal(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseWithdrawal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReverseWithdrawal indicates an expected call of ReverseWithdrawal.
+func (mr *MockOrderServiceMockRecorder) ReverseWithdrawal(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseWithdrawal", reflect.TypeOf((*MockOrderService)(nil).ReverseWithdrawal), arg0, arg1, arg2)
+}
+
 // Withdraw mocks base method.
-func (m *MockOrderService) Withdraw(arg0 context.Context, arg1 string, arg2 int, arg3 *domain.User) error {
+func (m *MockOrderService) Withdraw(arg0 context.Context, arg1 string, arg2 domain.Money, arg3 *domain.User) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Withdraw", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
@@ -175,3 +321,498 @@ func (mr *MockOrderServiceMockRecorder) Withdraw(arg0, arg1, arg2, arg3 interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockOrderService)(nil).Withdraw), arg0, arg1, arg2, arg3)
 }
+
+// MockHoldService is a mock of HoldService interface.
+type MockHoldService struct {
+	ctrl     *gomock.Controller
+	recorder *MockHoldServiceMockRecorder
+}
+
+// MockHoldServiceMockRecorder is the mock recorder for MockHoldService.
+type MockHoldServiceMockRecorder struct {
+	mock *MockHoldService
+}
+
+// NewMockHoldService creates a new mock instance.
+func NewMockHoldService(ctrl *gomock.Controller) *MockHoldService {
+	mock := &MockHoldService{ctrl: ctrl}
+	mock.recorder = &MockHoldServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHoldService) EXPECT() *MockHoldServiceMockRecorder {
+	return m.recorder
+}
+
+// CaptureHold mocks base method.
+func (m *MockHoldService) CaptureHold(arg0 context.Context, arg1 int, arg2 *domain.User) (domain.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockHoldServiceMockRecorder) CaptureHold(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockHoldService)(nil).CaptureHold), arg0, arg1, arg2)
+}
+
+// CreateHold mocks base method.
+func (m *MockHoldService) CreateHold(arg0 context.Context, arg1 string, arg2 domain.Money, arg3 *domain.User) (domain.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockHoldServiceMockRecorder) CreateHold(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockHoldService)(nil).CreateHold), arg0, arg1, arg2, arg3)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockHoldService) ReleaseHold(arg0 context.Context, arg1 int, arg2 *domain.User) (domain.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockHoldServiceMockRecorder) ReleaseHold(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockHoldService)(nil).ReleaseHold), arg0, arg1, arg2)
+}
+
+// MockTransferService is a mock of TransferService interface.
+type MockTransferService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransferServiceMockRecorder
+}
+
+// MockTransferServiceMockRecorder is the mock recorder for MockTransferService.
+type MockTransferServiceMockRecorder struct {
+	mock *MockTransferService
+}
+
+// NewMockTransferService creates a new mock instance.
+func NewMockTransferService(ctrl *gomock.Controller) *MockTransferService {
+	mock := &MockTransferService{ctrl: ctrl}
+	mock.recorder = &MockTransferServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransferService) EXPECT() *MockTransferServiceMockRecorder {
+	return m.recorder
+}
+
+// Transfer mocks base method.
+func (m *MockTransferService) Transfer(arg0 context.Context, arg1 string, arg2 domain.Money, arg3 *domain.User) (domain.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockTransferServiceMockRecorder) Transfer(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockTransferService)(nil).Transfer), arg0, arg1, arg2, arg3)
+}
+
+// MockTierService is a mock of TierService interface.
+type MockTierService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTierServiceMockRecorder
+}
+
+// MockTierServiceMockRecorder is the mock recorder for MockTierService.
+type MockTierServiceMockRecorder struct {
+	mock *MockTierService
+}
+
+// NewMockTierService creates a new mock instance.
+func NewMockTierService(ctrl *gomock.Controller) *MockTierService {
+	mock := &MockTierService{ctrl: ctrl}
+	mock.recorder = &MockTierServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTierService) EXPECT() *MockTierServiceMockRecorder {
+	return m.recorder
+}
+
+// GetTierHistory mocks base method.
+func (m *MockTierService) GetTierHistory(arg0 context.Context, arg1 *domain.User) ([]domain.TierChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTierHistory", arg0, arg1)
+	ret0, _ := ret[0].([]domain.TierChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTierHistory indicates an expected call of GetTierHistory.
+func (mr *MockTierServiceMockRecorder) GetTierHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTierHistory", reflect.TypeOf((*MockTierService)(nil).GetTierHistory), arg0, arg1)
+}
+
+// MockReferralService is a mock of ReferralService interface.
+type MockReferralService struct {
+	ctrl     *gomock.Controller
+	recorder *MockReferralServiceMockRecorder
+}
+
+// MockReferralServiceMockRecorder is the mock recorder for MockReferralService.
+type MockReferralServiceMockRecorder struct {
+	mock *MockReferralService
+}
+
+// NewMockReferralService creates a new mock instance.
+func NewMockReferralService(ctrl *gomock.Controller) *MockReferralService {
+	mock := &MockReferralService{ctrl: ctrl}
+	mock.recorder = &MockReferralServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReferralService) EXPECT() *MockReferralServiceMockRecorder {
+	return m.recorder
+}
+
+// AddReferral mocks base method.
+func (m *MockReferralService) AddReferral(arg0 context.Context, arg1, arg2 domain.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReferral", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddReferral indicates an expected call of AddReferral.
+func (mr *MockReferralServiceMockRecorder) AddReferral(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReferral", reflect.TypeOf((*MockReferralService)(nil).AddReferral), arg0, arg1, arg2)
+}
+
+// CheckReferralCode mocks base method.
+func (m *MockReferralService) CheckReferralCode(arg0 context.Context, arg1 string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckReferralCode", arg0, arg1)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckReferralCode indicates an expected call of CheckReferralCode.
+func (mr *MockReferralServiceMockRecorder) CheckReferralCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckReferralCode", reflect.TypeOf((*MockReferralService)(nil).CheckReferralCode), arg0, arg1)
+}
+
+// GetReferrals mocks base method.
+func (m *MockReferralService) GetReferrals(arg0 context.Context, arg1 *domain.User) ([]domain.Referral, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReferrals", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Referral)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReferrals indicates an expected call of GetReferrals.
+func (mr *MockReferralServiceMockRecorder) GetReferrals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReferrals", reflect.TypeOf((*MockReferralService)(nil).GetReferrals), arg0, arg1)
+}
+
+// MockPromoService is a mock of PromoService interface.
+type MockPromoService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPromoServiceMockRecorder
+}
+
+// MockPromoServiceMockRecorder is the mock recorder for MockPromoService.
+type MockPromoServiceMockRecorder struct {
+	mock *MockPromoService
+}
+
+// NewMockPromoService creates a new mock instance.
+func NewMockPromoService(ctrl *gomock.Controller) *MockPromoService {
+	mock := &MockPromoService{ctrl: ctrl}
+	mock.recorder = &MockPromoServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPromoService) EXPECT() *MockPromoServiceMockRecorder {
+	return m.recorder
+}
+
+// CreatePromoCode mocks base method.
+func (m *MockPromoService) CreatePromoCode(arg0 context.Context, arg1 domain.PromoCode) (domain.PromoCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePromoCode", arg0, arg1)
+	ret0, _ := ret[0].(domain.PromoCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePromoCode indicates an expected call of CreatePromoCode.
+func (mr *MockPromoServiceMockRecorder) CreatePromoCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePromoCode", reflect.TypeOf((*MockPromoService)(nil).CreatePromoCode), arg0, arg1)
+}
+
+// RedeemPromoCode mocks base method.
+func (m *MockPromoService) RedeemPromoCode(arg0 context.Context, arg1 string, arg2 *domain.User) (domain.PromoRedemption, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemPromoCode", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.PromoRedemption)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemPromoCode indicates an expected call of RedeemPromoCode.
+func (mr *MockPromoServiceMockRecorder) RedeemPromoCode(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemPromoCode", reflect.TypeOf((*MockPromoService)(nil).RedeemPromoCode), arg0, arg1, arg2)
+}
+
+// MockIdempotencyService is a mock of IdempotencyService interface.
+type MockIdempotencyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyServiceMockRecorder
+}
+
+// MockIdempotencyServiceMockRecorder is the mock recorder for MockIdempotencyService.
+type MockIdempotencyServiceMockRecorder struct {
+	mock *MockIdempotencyService
+}
+
+// NewMockIdempotencyService creates a new mock instance.
+func NewMockIdempotencyService(ctrl *gomock.Controller) *MockIdempotencyService {
+	mock := &MockIdempotencyService{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyService) EXPECT() *MockIdempotencyServiceMockRecorder {
+	return m.recorder
+}
+
+// Abort mocks base method.
+func (m *MockIdempotencyService) Abort(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abort", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Abort indicates an expected call of Abort.
+func (mr *MockIdempotencyServiceMockRecorder) Abort(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abort", reflect.TypeOf((*MockIdempotencyService)(nil).Abort), arg0, arg1, arg2)
+}
+
+// Begin mocks base method.
+func (m *MockIdempotencyService) Begin(arg0 context.Context, arg1 int, arg2, arg3 string) (domain.IdempotencyRecord, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.IdempotencyRecord)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockIdempotencyServiceMockRecorder) Begin(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockIdempotencyService)(nil).Begin), arg0, arg1, arg2, arg3)
+}
+
+// Complete mocks base method.
+func (m *MockIdempotencyService) Complete(arg0 context.Context, arg1 domain.IdempotencyRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyServiceMockRecorder) Complete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotencyService)(nil).Complete), arg0, arg1)
+}
+
+// MockAccrualService is a mock of AccrualService interface.
+type MockAccrualService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccrualServiceMockRecorder
+}
+
+// MockAccrualServiceMockRecorder is the mock recorder for MockAccrualService.
+type MockAccrualServiceMockRecorder struct {
+	mock *MockAccrualService
+}
+
+// NewMockAccrualService creates a new mock instance.
+func NewMockAccrualService(ctrl *gomock.Controller) *MockAccrualService {
+	mock := &MockAccrualService{ctrl: ctrl}
+	mock.recorder = &MockAccrualServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccrualService) EXPECT() *MockAccrualServiceMockRecorder {
+	return m.recorder
+}
+
+// CheckAccrual mocks base method.
+func (m *MockAccrualService) CheckAccrual(arg0 context.Context, arg1 string) (ports.AccrualResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckAccrual", arg0, arg1)
+	ret0, _ := ret[0].(ports.AccrualResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckAccrual indicates an expected call of CheckAccrual.
+func (mr *MockAccrualServiceMockRecorder) CheckAccrual(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAccrual", reflect.TypeOf((*MockAccrualService)(nil).CheckAccrual), arg0, arg1)
+}
+
+// MockNotifier is a mock of Notifier interface.
+type MockNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotifierMockRecorder
+}
+
+// MockNotifierMockRecorder is the mock recorder for MockNotifier.
+type MockNotifierMockRecorder struct {
+	mock *MockNotifier
+}
+
+// NewMockNotifier creates a new mock instance.
+func NewMockNotifier(ctrl *gomock.Controller) *MockNotifier {
+	mock := &MockNotifier{ctrl: ctrl}
+	mock.recorder = &MockNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotifier) EXPECT() *MockNotifierMockRecorder {
+	return m.recorder
+}
+
+// Notify mocks base method.
+func (m *MockNotifier) Notify(arg0 context.Context, arg1 domain.Notification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Notify", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockNotifierMockRecorder) Notify(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockNotifier)(nil).Notify), arg0, arg1)
+}
+
+// MockAccrualRules is a mock of AccrualRules interface.
+type MockAccrualRules struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccrualRulesMockRecorder
+}
+
+// MockAccrualRulesMockRecorder is the mock recorder for MockAccrualRules.
+type MockAccrualRulesMockRecorder struct {
+	mock *MockAccrualRules
+}
+
+// NewMockAccrualRules creates a new mock instance.
+func NewMockAccrualRules(ctrl *gomock.Controller) *MockAccrualRules {
+	mock := &MockAccrualRules{ctrl: ctrl}
+	mock.recorder = &MockAccrualRulesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccrualRules) EXPECT() *MockAccrualRulesMockRecorder {
+	return m.recorder
+}
+
+// ApplyRules mocks base method.
+func (m *MockAccrualRules) ApplyRules(arg0 context.Context, arg1 domain.Order, arg2 time.Time, arg3 ports.AccrualStatsFunc) (domain.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyRules", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyRules indicates an expected call of ApplyRules.
+func (mr *MockAccrualRulesMockRecorder) ApplyRules(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyRules", reflect.TypeOf((*MockAccrualRules)(nil).ApplyRules), arg0, arg1, arg2, arg3)
+}
+
+// GrantSignupBonus mocks base method.
+func (m *MockAccrualRules) GrantSignupBonus(arg0 context.Context, arg1 domain.User, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantSignupBonus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantSignupBonus indicates an expected call of GrantSignupBonus.
+func (mr *MockAccrualRulesMockRecorder) GrantSignupBonus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantSignupBonus", reflect.TypeOf((*MockAccrualRules)(nil).GrantSignupBonus), arg0, arg1, arg2)
+}
+
+// MockAccrualProcessor is a mock of AccrualProcessor interface.
+type MockAccrualProcessor struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccrualProcessorMockRecorder
+}
+
+// MockAccrualProcessorMockRecorder is the mock recorder for MockAccrualProcessor.
+type MockAccrualProcessorMockRecorder struct {
+	mock *MockAccrualProcessor
+}
+
+// NewMockAccrualProcessor creates a new mock instance.
+func NewMockAccrualProcessor(ctrl *gomock.Controller) *MockAccrualProcessor {
+	mock := &MockAccrualProcessor{ctrl: ctrl}
+	mock.recorder = &MockAccrualProcessorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccrualProcessor) EXPECT() *MockAccrualProcessorMockRecorder {
+	return m.recorder
+}
+
+// ProcessAccrual mocks base method.
+func (m *MockAccrualProcessor) ProcessAccrual(arg0 context.Context, arg1 ports.AccrualResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessAccrual", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProcessAccrual indicates an expected call of ProcessAccrual.
+func (mr *MockAccrualProcessorMockRecorder) ProcessAccrual(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessAccrual", reflect.TypeOf((*MockAccrualProcessor)(nil).ProcessAccrual), arg0, arg1)
+}
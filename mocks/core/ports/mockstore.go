@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: gophermart/internal/core/ports (interfaces: UserStore,OrderStore,WithdrawnStore)
+// Source: gophermart/internal/core/ports (interfaces: UserStore,TokenStore,OrderStore,WithdrawnStore,HoldStore,TransferStore,TierStore,ReferralStore,PromoStore,LedgerStore,IdempotencyStore)
 
 // Package ports is a generated GoMock package.
 package ports
@@ -7,7 +7,9 @@ package ports
 import (
 	context "context"
 	domain "gophermart/internal/core/domain"
+	ports "gophermart/internal/core/ports"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -49,6 +51,20 @@ func (mr *MockUserStoreMockRecorder) AddNewUser(arg0, arg1, arg2 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewUser", reflect.TypeOf((*MockUserStore)(nil).AddNewUser), arg0, arg1, arg2)
 }
 
+// CreatePasswordReset mocks base method.
+func (m *MockUserStore) CreatePasswordReset(arg0 context.Context, arg1 domain.PasswordReset) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordReset", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePasswordReset indicates an expected call of CreatePasswordReset.
+func (mr *MockUserStoreMockRecorder) CreatePasswordReset(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordReset", reflect.TypeOf((*MockUserStore)(nil).CreatePasswordReset), arg0, arg1)
+}
+
 // GetUser mocks base method.
 func (m *MockUserStore) GetUser(arg0 context.Context, arg1 string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +80,145 @@ func (mr *MockUserStoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserStore)(nil).GetUser), arg0, arg1)
 }
 
+// ResetPassword mocks base method.
+func (m *MockUserStore) ResetPassword(arg0 context.Context, arg1, arg2 string, arg3 time.Time) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserStoreMockRecorder) ResetPassword(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUserStore)(nil).ResetPassword), arg0, arg1, arg2, arg3)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserStore) UpdatePassword(arg0 context.Context, arg1 int, arg2 string, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserStoreMockRecorder) UpdatePassword(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserStore)(nil).UpdatePassword), arg0, arg1, arg2, arg3)
+}
+
+// MockTokenStore is a mock of TokenStore interface.
+type MockTokenStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenStoreMockRecorder
+}
+
+// MockTokenStoreMockRecorder is the mock recorder for MockTokenStore.
+type MockTokenStoreMockRecorder struct {
+	mock *MockTokenStore
+}
+
+// NewMockTokenStore creates a new mock instance.
+func NewMockTokenStore(ctrl *gomock.Controller) *MockTokenStore {
+	mock := &MockTokenStore{ctrl: ctrl}
+	mock.recorder = &MockTokenStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenStore) EXPECT() *MockTokenStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockTokenStore) CreateRefreshToken(arg0 context.Context, arg1 domain.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockTokenStoreMockRecorder) CreateRefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockTokenStore)(nil).CreateRefreshToken), arg0, arg1)
+}
+
+// DeleteExpired mocks base method.
+func (m *MockTokenStore) DeleteExpired(arg0 context.Context, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockTokenStoreMockRecorder) DeleteExpired(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockTokenStore)(nil).DeleteExpired), arg0, arg1)
+}
+
+// IsTokenRevoked mocks base method.
+func (m *MockTokenStore) IsTokenRevoked(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockTokenStoreMockRecorder) IsTokenRevoked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockTokenStore)(nil).IsTokenRevoked), arg0, arg1)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockTokenStore) RevokeAllSessions(arg0 context.Context, arg1 int, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockTokenStoreMockRecorder) RevokeAllSessions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockTokenStore)(nil).RevokeAllSessions), arg0, arg1, arg2)
+}
+
+// RevokeSession mocks base method.
+func (m *MockTokenStore) RevokeSession(arg0 context.Context, arg1 domain.Session, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockTokenStoreMockRecorder) RevokeSession(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockTokenStore)(nil).RevokeSession), arg0, arg1, arg2)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockTokenStore) RotateRefreshToken(arg0 context.Context, arg1 string, arg2 domain.RefreshToken) (domain.User, domain.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(domain.RefreshToken)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockTokenStoreMockRecorder) RotateRefreshToken(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockTokenStore)(nil).RotateRefreshToken), arg0, arg1, arg2)
+}
+
 // MockOrderStore is a mock of OrderStore interface.
 type MockOrderStore struct {
 	ctrl     *gomock.Controller
@@ -101,19 +256,19 @@ func (mr *MockOrderStoreMockRecorder) AddNewOrder(arg0, arg1, arg2 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewOrder", reflect.TypeOf((*MockOrderStore)(nil).AddNewOrder), arg0, arg1, arg2)
 }
 
-// GetAllNotFinished mocks base method.
-func (m *MockOrderStore) GetAllNotFinished(arg0 context.Context) ([]domain.Order, error) {
+// ClaimDueOrders mocks base method.
+func (m *MockOrderStore) ClaimDueOrders(arg0 context.Context, arg1 time.Time, arg2 int, arg3 time.Duration) ([]domain.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllNotFinished", arg0)
+	ret := m.ctrl.Call(m, "ClaimDueOrders", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]domain.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAllNotFinished indicates an expected call of GetAllNotFinished.
-func (mr *MockOrderStoreMockRecorder) GetAllNotFinished(arg0 interface{}) *gomock.Call {
+// ClaimDueOrders indicates an expected call of ClaimDueOrders.
+func (mr *MockOrderStoreMockRecorder) ClaimDueOrders(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllNotFinished", reflect.TypeOf((*MockOrderStore)(nil).GetAllNotFinished), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueOrders", reflect.TypeOf((*MockOrderStore)(nil).ClaimDueOrders), arg0, arg1, arg2, arg3)
 }
 
 // GetAllOrders mocks base method.
@@ -147,17 +302,17 @@ func (mr *MockOrderStoreMockRecorder) GetOrder(arg0, arg1 interface{}) *gomock.C
 }
 
 // UpdateOrders mocks base method.
-func (m *MockOrderStore) UpdateOrders(arg0 context.Context, arg1 []domain.Order) error {
+func (m *MockOrderStore) UpdateOrders(arg0 context.Context, arg1 []domain.Order, arg2 ports.ApplyRulesFunc) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateOrders", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateOrders", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateOrders indicates an expected call of UpdateOrders.
-func (mr *MockOrderStoreMockRecorder) UpdateOrders(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockOrderStoreMockRecorder) UpdateOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrders", reflect.TypeOf((*MockOrderStore)(nil).UpdateOrders), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrders", reflect.TypeOf((*MockOrderStore)(nil).UpdateOrders), arg0, arg1, arg2)
 }
 
 // MockWithdrawnStore is a mock of WithdrawnStore interface.
@@ -184,17 +339,31 @@ func (m *MockWithdrawnStore) EXPECT() *MockWithdrawnStoreMockRecorder {
 }
 
 // AddNewWithdrawn mocks base method.
-func (m *MockWithdrawnStore) AddNewWithdrawn(arg0 context.Context, arg1 string, arg2, arg3 int) error {
+func (m *MockWithdrawnStore) AddNewWithdrawn(arg0 context.Context, arg1 string, arg2 domain.Money, arg3 int, arg4 domain.WithdrawalLimits) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddNewWithdrawn", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "AddNewWithdrawn", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddNewWithdrawn indicates an expected call of AddNewWithdrawn.
-func (mr *MockWithdrawnStoreMockRecorder) AddNewWithdrawn(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockWithdrawnStoreMockRecorder) AddNewWithdrawn(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewWithdrawn", reflect.TypeOf((*MockWithdrawnStore)(nil).AddNewWithdrawn), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewWithdrawn", reflect.TypeOf((*MockWithdrawnStore)(nil).AddNewWithdrawn), arg0, arg1, arg2, arg3, arg4)
+}
+
+// CompleteWithdrawn mocks base method.
+func (m *MockWithdrawnStore) CompleteWithdrawn(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteWithdrawn", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteWithdrawn indicates an expected call of CompleteWithdrawn.
+func (mr *MockWithdrawnStoreMockRecorder) CompleteWithdrawn(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteWithdrawn", reflect.TypeOf((*MockWithdrawnStore)(nil).CompleteWithdrawn), arg0, arg1)
 }
 
 // GetAllWithdrawals mocks base method.
@@ -211,3 +380,550 @@ func (mr *MockWithdrawnStoreMockRecorder) GetAllWithdrawals(arg0, arg1 interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllWithdrawals", reflect.TypeOf((*MockWithdrawnStore)(nil).GetAllWithdrawals), arg0, arg1)
 }
+
+// ReverseWithdrawn mocks base method.
+func (m *MockWithdrawnStore) ReverseWithdrawn(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseWithdrawn", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReverseWithdrawn indicates an expected call of ReverseWithdrawn.
+func (mr *MockWithdrawnStoreMockRecorder) ReverseWithdrawn(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseWithdrawn", reflect.TypeOf((*MockWithdrawnStore)(nil).ReverseWithdrawn), arg0, arg1, arg2)
+}
+
+// MockHoldStore is a mock of HoldStore interface.
+type MockHoldStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockHoldStoreMockRecorder
+}
+
+// MockHoldStoreMockRecorder is the mock recorder for MockHoldStore.
+type MockHoldStoreMockRecorder struct {
+	mock *MockHoldStore
+}
+
+// NewMockHoldStore creates a new mock instance.
+func NewMockHoldStore(ctrl *gomock.Controller) *MockHoldStore {
+	mock := &MockHoldStore{ctrl: ctrl}
+	mock.recorder = &MockHoldStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHoldStore) EXPECT() *MockHoldStoreMockRecorder {
+	return m.recorder
+}
+
+// CaptureHold mocks base method.
+func (m *MockHoldStore) CaptureHold(arg0 context.Context, arg1, arg2 int, arg3 time.Time) (domain.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockHoldStoreMockRecorder) CaptureHold(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockHoldStore)(nil).CaptureHold), arg0, arg1, arg2, arg3)
+}
+
+// CreateHold mocks base method.
+func (m *MockHoldStore) CreateHold(arg0 context.Context, arg1 domain.Hold, arg2 domain.WithdrawalLimits) (domain.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockHoldStoreMockRecorder) CreateHold(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockHoldStore)(nil).CreateHold), arg0, arg1, arg2)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockHoldStore) ExpireHolds(arg0 context.Context, arg1 time.Time, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockHoldStoreMockRecorder) ExpireHolds(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockHoldStore)(nil).ExpireHolds), arg0, arg1, arg2)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockHoldStore) ReleaseHold(arg0 context.Context, arg1, arg2 int, arg3 time.Time) (domain.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockHoldStoreMockRecorder) ReleaseHold(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockHoldStore)(nil).ReleaseHold), arg0, arg1, arg2, arg3)
+}
+
+// MockTransferStore is a mock of TransferStore interface.
+type MockTransferStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransferStoreMockRecorder
+}
+
+// MockTransferStoreMockRecorder is the mock recorder for MockTransferStore.
+type MockTransferStoreMockRecorder struct {
+	mock *MockTransferStore
+}
+
+// NewMockTransferStore creates a new mock instance.
+func NewMockTransferStore(ctrl *gomock.Controller) *MockTransferStore {
+	mock := &MockTransferStore{ctrl: ctrl}
+	mock.recorder = &MockTransferStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransferStore) EXPECT() *MockTransferStoreMockRecorder {
+	return m.recorder
+}
+
+// AddNewTransfer mocks base method.
+func (m *MockTransferStore) AddNewTransfer(arg0 context.Context, arg1 int, arg2 string, arg3 domain.Money, arg4 domain.TransferLimits) (domain.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewTransfer", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(domain.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddNewTransfer indicates an expected call of AddNewTransfer.
+func (mr *MockTransferStoreMockRecorder) AddNewTransfer(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewTransfer", reflect.TypeOf((*MockTransferStore)(nil).AddNewTransfer), arg0, arg1, arg2, arg3, arg4)
+}
+
+// MockTierStore is a mock of TierStore interface.
+type MockTierStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockTierStoreMockRecorder
+}
+
+// MockTierStoreMockRecorder is the mock recorder for MockTierStore.
+type MockTierStoreMockRecorder struct {
+	mock *MockTierStore
+}
+
+// NewMockTierStore creates a new mock instance.
+func NewMockTierStore(ctrl *gomock.Controller) *MockTierStore {
+	mock := &MockTierStore{ctrl: ctrl}
+	mock.recorder = &MockTierStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTierStore) EXPECT() *MockTierStoreMockRecorder {
+	return m.recorder
+}
+
+// ChangeTier mocks base method.
+func (m *MockTierStore) ChangeTier(arg0 context.Context, arg1 domain.TierChange) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeTier", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeTier indicates an expected call of ChangeTier.
+func (mr *MockTierStoreMockRecorder) ChangeTier(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeTier", reflect.TypeOf((*MockTierStore)(nil).ChangeTier), arg0, arg1)
+}
+
+// GetTierAccruals mocks base method.
+func (m *MockTierStore) GetTierAccruals(arg0 context.Context, arg1 time.Time) ([]domain.TierAccrual, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTierAccruals", arg0, arg1)
+	ret0, _ := ret[0].([]domain.TierAccrual)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTierAccruals indicates an expected call of GetTierAccruals.
+func (mr *MockTierStoreMockRecorder) GetTierAccruals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTierAccruals", reflect.TypeOf((*MockTierStore)(nil).GetTierAccruals), arg0, arg1)
+}
+
+// GetTierChanges mocks base method.
+func (m *MockTierStore) GetTierChanges(arg0 context.Context, arg1 int) ([]domain.TierChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTierChanges", arg0, arg1)
+	ret0, _ := ret[0].([]domain.TierChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTierChanges indicates an expected call of GetTierChanges.
+func (mr *MockTierStoreMockRecorder) GetTierChanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTierChanges", reflect.TypeOf((*MockTierStore)(nil).GetTierChanges), arg0, arg1)
+}
+
+// GetUserTier mocks base method.
+func (m *MockTierStore) GetUserTier(arg0 context.Context, arg1 int) (domain.TierName, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTier", arg0, arg1)
+	ret0, _ := ret[0].(domain.TierName)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTier indicates an expected call of GetUserTier.
+func (mr *MockTierStoreMockRecorder) GetUserTier(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTier", reflect.TypeOf((*MockTierStore)(nil).GetUserTier), arg0, arg1)
+}
+
+// MockReferralStore is a mock of ReferralStore interface.
+type MockReferralStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockReferralStoreMockRecorder
+}
+
+// MockReferralStoreMockRecorder is the mock recorder for MockReferralStore.
+type MockReferralStoreMockRecorder struct {
+	mock *MockReferralStore
+}
+
+// NewMockReferralStore creates a new mock instance.
+func NewMockReferralStore(ctrl *gomock.Controller) *MockReferralStore {
+	mock := &MockReferralStore{ctrl: ctrl}
+	mock.recorder = &MockReferralStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReferralStore) EXPECT() *MockReferralStoreMockRecorder {
+	return m.recorder
+}
+
+// AddReferral mocks base method.
+func (m *MockReferralStore) AddReferral(arg0 context.Context, arg1 domain.Referral, arg2 int) (domain.Referral, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReferral", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.Referral)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddReferral indicates an expected call of AddReferral.
+func (mr *MockReferralStoreMockRecorder) AddReferral(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReferral", reflect.TypeOf((*MockReferralStore)(nil).AddReferral), arg0, arg1, arg2)
+}
+
+// CountReferrals mocks base method.
+func (m *MockReferralStore) CountReferrals(arg0 context.Context, arg1 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountReferrals", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountReferrals indicates an expected call of CountReferrals.
+func (mr *MockReferralStoreMockRecorder) CountReferrals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountReferrals", reflect.TypeOf((*MockReferralStore)(nil).CountReferrals), arg0, arg1)
+}
+
+// GetReferrals mocks base method.
+func (m *MockReferralStore) GetReferrals(arg0 context.Context, arg1 int) ([]domain.Referral, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReferrals", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Referral)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReferrals indicates an expected call of GetReferrals.
+func (mr *MockReferralStoreMockRecorder) GetReferrals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReferrals", reflect.TypeOf((*MockReferralStore)(nil).GetReferrals), arg0, arg1)
+}
+
+// GetReferrer mocks base method.
+func (m *MockReferralStore) GetReferrer(arg0 context.Context, arg1 string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReferrer", arg0, arg1)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReferrer indicates an expected call of GetReferrer.
+func (mr *MockReferralStoreMockRecorder) GetReferrer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReferrer", reflect.TypeOf((*MockReferralStore)(nil).GetReferrer), arg0, arg1)
+}
+
+// MockPromoStore is a mock of PromoStore interface.
+type MockPromoStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockPromoStoreMockRecorder
+}
+
+// MockPromoStoreMockRecorder is the mock recorder for MockPromoStore.
+type MockPromoStoreMockRecorder struct {
+	mock *MockPromoStore
+}
+
+// NewMockPromoStore creates a new mock instance.
+func NewMockPromoStore(ctrl *gomock.Controller) *MockPromoStore {
+	mock := &MockPromoStore{ctrl: ctrl}
+	mock.recorder = &MockPromoStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPromoStore) EXPECT() *MockPromoStoreMockRecorder {
+	return m.recorder
+}
+
+// CreatePromoCode mocks base method.
+func (m *MockPromoStore) CreatePromoCode(arg0 context.Context, arg1 domain.PromoCode) (domain.PromoCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePromoCode", arg0, arg1)
+	ret0, _ := ret[0].(domain.PromoCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePromoCode indicates an expected call of CreatePromoCode.
+func (mr *MockPromoStoreMockRecorder) CreatePromoCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePromoCode", reflect.TypeOf((*MockPromoStore)(nil).CreatePromoCode), arg0, arg1)
+}
+
+// RedeemPromoCode mocks base method.
+func (m *MockPromoStore) RedeemPromoCode(arg0 context.Context, arg1 string, arg2 int, arg3 time.Time) (domain.PromoRedemption, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemPromoCode", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.PromoRedemption)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemPromoCode indicates an expected call of RedeemPromoCode.
+func (mr *MockPromoStoreMockRecorder) RedeemPromoCode(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemPromoCode", reflect.TypeOf((*MockPromoStore)(nil).RedeemPromoCode), arg0, arg1, arg2, arg3)
+}
+
+// MockLedgerStore is a mock of LedgerStore interface.
+type MockLedgerStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockLedgerStoreMockRecorder
+}
+
+// MockLedgerStoreMockRecorder is the mock recorder for MockLedgerStore.
+type MockLedgerStoreMockRecorder struct {
+	mock *MockLedgerStore
+}
+
+// NewMockLedgerStore creates a new mock instance.
+func NewMockLedgerStore(ctrl *gomock.Controller) *MockLedgerStore {
+	mock := &MockLedgerStore{ctrl: ctrl}
+	mock.recorder = &MockLedgerStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLedgerStore) EXPECT() *MockLedgerStoreMockRecorder {
+	return m.recorder
+}
+
+// ExpirePoints mocks base method.
+func (m *MockLedgerStore) ExpirePoints(arg0 context.Context, arg1 int, arg2 domain.ExpiryPolicy, arg3 time.Time) (domain.Money, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpirePoints", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.Money)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpirePoints indicates an expected call of ExpirePoints.
+func (mr *MockLedgerStoreMockRecorder) ExpirePoints(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpirePoints", reflect.TypeOf((*MockLedgerStore)(nil).ExpirePoints), arg0, arg1, arg2, arg3)
+}
+
+// GetBalance mocks base method.
+func (m *MockLedgerStore) GetBalance(arg0 context.Context, arg1 int) (domain.UserBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", arg0, arg1)
+	ret0, _ := ret[0].(domain.UserBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockLedgerStoreMockRecorder) GetBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockLedgerStore)(nil).GetBalance), arg0, arg1)
+}
+
+// GetExpiredPointsOwners mocks base method.
+func (m *MockLedgerStore) GetExpiredPointsOwners(arg0 context.Context, arg1 time.Time) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredPointsOwners", arg0, arg1)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredPointsOwners indicates an expected call of GetExpiredPointsOwners.
+func (mr *MockLedgerStoreMockRecorder) GetExpiredPointsOwners(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredPointsOwners", reflect.TypeOf((*MockLedgerStore)(nil).GetExpiredPointsOwners), arg0, arg1)
+}
+
+// GetHistory mocks base method.
+func (m *MockLedgerStore) GetHistory(arg0 context.Context, arg1 int) ([]domain.LedgerEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1)
+	ret0, _ := ret[0].([]domain.LedgerEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockLedgerStoreMockRecorder) GetHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockLedgerStore)(nil).GetHistory), arg0, arg1)
+}
+
+// GetLots mocks base method.
+func (m *MockLedgerStore) GetLots(arg0 context.Context, arg1 int, arg2 time.Time) ([]domain.PointsLot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLots", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]domain.PointsLot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLots indicates an expected call of GetLots.
+func (mr *MockLedgerStoreMockRecorder) GetLots(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLots", reflect.TypeOf((*MockLedgerStore)(nil).GetLots), arg0, arg1, arg2)
+}
+
+// PostTransaction mocks base method.
+func (m *MockLedgerStore) PostTransaction(arg0 context.Context, arg1 domain.LedgerTransaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostTransaction", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PostTransaction indicates an expected call of PostTransaction.
+func (mr *MockLedgerStoreMockRecorder) PostTransaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostTransaction", reflect.TypeOf((*MockLedgerStore)(nil).PostTransaction), arg0, arg1)
+}
+
+// MockIdempotencyStore is a mock of IdempotencyStore interface.
+type MockIdempotencyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyStoreMockRecorder
+}
+
+// MockIdempotencyStoreMockRecorder is the mock recorder for MockIdempotencyStore.
+type MockIdempotencyStoreMockRecorder struct {
+	mock *MockIdempotencyStore
+}
+
+// NewMockIdempotencyStore creates a new mock instance.
+func NewMockIdempotencyStore(ctrl *gomock.Controller) *MockIdempotencyStore {
+	mock := &MockIdempotencyStore{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyStore) EXPECT() *MockIdempotencyStoreMockRecorder {
+	return m.recorder
+}
+
+// CompleteRecord mocks base method.
+func (m *MockIdempotencyStore) CompleteRecord(arg0 context.Context, arg1 domain.IdempotencyRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteRecord", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteRecord indicates an expected call of CompleteRecord.
+func (mr *MockIdempotencyStoreMockRecorder) CompleteRecord(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteRecord", reflect.TypeOf((*MockIdempotencyStore)(nil).CompleteRecord), arg0, arg1)
+}
+
+// CreateRecord mocks base method.
+func (m *MockIdempotencyStore) CreateRecord(arg0 context.Context, arg1 domain.IdempotencyRecord, arg2 time.Time) (domain.IdempotencyRecord, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecord", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.IdempotencyRecord)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateRecord indicates an expected call of CreateRecord.
+func (mr *MockIdempotencyStoreMockRecorder) CreateRecord(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecord", reflect.TypeOf((*MockIdempotencyStore)(nil).CreateRecord), arg0, arg1, arg2)
+}
+
+// DeleteExpired mocks base method.
+func (m *MockIdempotencyStore) DeleteExpired(arg0 context.Context, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockIdempotencyStoreMockRecorder) DeleteExpired(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockIdempotencyStore)(nil).DeleteExpired), arg0, arg1)
+}
+
+// DeleteRecord mocks base method.
+func (m *MockIdempotencyStore) DeleteRecord(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecord", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecord indicates an expected call of DeleteRecord.
+func (mr *MockIdempotencyStoreMockRecorder) DeleteRecord(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecord", reflect.TypeOf((*MockIdempotencyStore)(nil).DeleteRecord), arg0, arg1, arg2)
+}
@@ -1,7 +1,7 @@
 #!/usr/bin/env sh
 
 mockgen -destination=mocks/core/ports/mockservice.go -package=ports gophermart/internal/core/ports \
-    UserService,OrderService
+    UserService,OrderService,HoldService,TransferService,TierService,ReferralService,PromoService,IdempotencyService,AccrualService,Notifier,AccrualRules,AccrualProcessor
 
 mockgen -destination=mocks/core/ports/mockstore.go   -package=ports gophermart/internal/core/ports \
-    UserStore,OrderStore,WithdrawnStore
+    UserStore,TokenStore,OrderStore,WithdrawnStore,HoldStore,TransferStore,TierStore,ReferralStore,PromoStore,LedgerStore,IdempotencyStore